@@ -0,0 +1,77 @@
+package loaders
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/shapes"
+)
+
+//LoadBPT loads Bezier patches from a .bpt file, the format the Utah teapot is usually shared in
+func LoadBPT(path string) ([]shapes.BezierPatch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBPT(f)
+}
+
+//ReadBPT reads Bezier patches in .bpt format: the number of patches, then for each patch its degree in u and v followed by its control points,
+//one x y z triple each, row by row.  Only bicubic patches (degree 3 3) are supported.  Pass the patches to shapes.NewBezierMesh to render them.
+func ReadBPT(r io.Reader) ([]shapes.BezierPatch, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	next := func() (float64, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return 0, err
+			}
+			return 0, fmt.Errorf("file is truncated")
+		}
+		v, err := strconv.ParseFloat(scanner.Text(), 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad number %q", scanner.Text())
+		}
+		return v, nil
+	}
+
+	count, err := next()
+	if err != nil {
+		return nil, fmt.Errorf("bpt: patch count: %v", err)
+	}
+	if count < 0 || count > math.MaxInt32 || count != math.Trunc(count) {
+		return nil, fmt.Errorf("bpt: bad patch count %v", count)
+	}
+
+	//the patches are grown as they are read, so a corrupt count fails when the file runs out rather than by allocating it up front
+	var patches []shapes.BezierPatch
+	for i := 0; i < int(count); i++ {
+		var degree [2]float64
+		for j := range degree {
+			if degree[j], err = next(); err != nil {
+				return nil, fmt.Errorf("bpt: patch %d: %v", i, err)
+			}
+		}
+		if degree != [2]float64{3, 3} {
+			return nil, fmt.Errorf("bpt: patch %d has degree %v %v, only bicubic (3 3) patches are supported", i, degree[0], degree[1])
+		}
+		var patch shapes.BezierPatch
+		for j := range patch {
+			var c [3]float64
+			for k := range c {
+				if c[k], err = next(); err != nil {
+					return nil, fmt.Errorf("bpt: patch %d control point %d: %v", i, j, err)
+				}
+			}
+			patch[j] = rays.Point{X: float32(c[0]), Y: float32(c[1]), Z: float32(c[2])}
+		}
+		patches = append(patches, patch)
+	}
+	return patches, nil
+}
@@ -0,0 +1,57 @@
+package loaders
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+//testBPTPatch is a flat bicubic patch, its 16 control points on a grid in the XY plane
+var testBPTPatch = func() string {
+	var b strings.Builder
+	b.WriteString("3 3\n")
+	for v := 0; v < 4; v++ {
+		for u := 0; u < 4; u++ {
+			fmt.Fprintf(&b, "%d %d 0\n", u, v)
+		}
+	}
+	return b.String()
+}()
+
+func TestReadBPT(t *testing.T) {
+	patches, err := ReadBPT(strings.NewReader("2\n" + testBPTPatch + testBPTPatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 2 {
+		t.Fatalf("read %d patches, want 2", len(patches))
+	}
+	if got := fmt.Sprint(patches[1][0], patches[1][5], patches[1][15]); got != "{0 0 0} {1 1 0} {3 3 0}" {
+		t.Errorf("control points 0, 5 and 15 = %s", got)
+	}
+}
+
+func TestReadBPTErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"empty", "", "patch count: file is truncated"},
+		{"bad count", "two\n", `patch count: bad number "two"`},
+		{"negative count", "-1\n", "bad patch count -1"},
+		{"fractional count", "1.5\n", "bad patch count 1.5"},
+		{"nan count", "nan\n", "bad patch count NaN"},
+		{"too large", "1e12\n", "bad patch count 1e+12"},
+		{"more than there are", "1000000\n" + testBPTPatch, "patch 1: file is truncated"},
+		{"not bicubic", "1\n2 3\n", "patch 0 has degree 2 3, only bicubic"},
+		{"truncated patch", "1\n" + testBPTPatch[:40], "patch 0 control point 6: file is truncated"},
+		{"bad coordinate", "1\n" + strings.Replace(testBPTPatch, "1 1 0", "1 x 0", 1), `patch 0 control point 5: bad number "x"`},
+	}
+	for _, test := range tests {
+		_, err := ReadBPT(strings.NewReader(test.file))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.want)
+		}
+	}
+}
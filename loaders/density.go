@@ -0,0 +1,59 @@
+package loaders
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/shapes"
+)
+
+//densityChunk is how many samples are read at a time
+const densityChunk = 1 << 16
+
+//LoadDensityGrid loads a heterogeneous medium from a raw density grid file.  See ReadDensityGrid for the format.
+func LoadDensityGrid(path string, origin rays.Point, size rays.Point) (shapes.GridMedium, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return shapes.GridMedium{}, err
+	}
+	defer f.Close()
+	return ReadDensityGrid(f, origin, size)
+}
+
+//ReadDensityGrid reads a density grid in a simple raw format: the number of samples along x, y and z as little-endian uint32s, followed by
+//that many little-endian float32 densities, x fastest, then y, then z.  The grid is stretched to span size from origin.  Set the medium's
+//Absorption and Scattering to make it visible.
+func ReadDensityGrid(r io.Reader, origin rays.Point, size rays.Point) (shapes.GridMedium, error) {
+	var counts [3]uint32
+	if err := binary.Read(r, binary.LittleEndian, &counts); err != nil {
+		return shapes.GridMedium{}, fmt.Errorf("density: reading grid size: %v", err)
+	}
+	total := uint64(counts[0]) * uint64(counts[1]) * uint64(counts[2])
+	if total == 0 || total > math.MaxInt32 {
+		return shapes.GridMedium{}, fmt.Errorf("density: grid size %dx%dx%d is not supported", counts[0], counts[1], counts[2])
+	}
+
+	//read in chunks so a corrupt size fails when the samples run out rather than by allocating them all up front
+	var densities []float32
+	chunk := make([]float32, densityChunk)
+	for remaining := total; remaining > 0; {
+		n := uint64(len(chunk))
+		if remaining < n {
+			n = remaining
+		}
+		if err := binary.Read(r, binary.LittleEndian, chunk[:n]); err != nil {
+			return shapes.GridMedium{}, fmt.Errorf("density: reading sample %d of %d: %v", uint64(len(densities)), total, err)
+		}
+		densities = append(densities, chunk[:n]...)
+		remaining -= n
+	}
+	grid, err := shapes.NewGridMedium(densities, int(counts[0]), int(counts[1]), int(counts[2]), origin, size)
+	if err != nil {
+		return shapes.GridMedium{}, fmt.Errorf("density: %v", err)
+	}
+	return grid, nil
+}
@@ -0,0 +1,58 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//densityFile returns a raw density grid with the given size and samples
+func densityFile(x uint32, y uint32, z uint32, samples ...float32) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, [3]uint32{x, y, z})
+	binary.Write(&b, binary.LittleEndian, samples)
+	return b.Bytes()
+}
+
+func TestReadDensityGrid(t *testing.T) {
+	file := densityFile(2, 1, 1, 0, 4)
+	grid, err := ReadDensityGrid(bytes.NewReader(file), rays.Point{}, rays.Point{X: 2, Y: 1, Z: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//samples sit at the centers of their cells
+	if d := grid.Density(rays.Point{X: 0.5, Y: 0.5, Z: 0.5}); d != 0 {
+		t.Errorf("density at the first sample = %v, want 0", d)
+	}
+	if d := grid.Density(rays.Point{X: 1, Y: 0.5, Z: 0.5}); d != 2 {
+		t.Errorf("density between the samples = %v, want 2", d)
+	}
+}
+
+func TestReadDensityGridErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+		want string
+	}{
+		{"truncated size", densityFile(2, 2, 2)[:6], "reading grid size"},
+		{"empty grid", densityFile(0, 4, 4), "grid size 0x4x4 is not supported"},
+		{"too large", densityFile(1<<20, 1<<20, 1<<20), "grid size 1048576x1048576x1048576 is not supported"},
+		{"truncated samples", densityFile(2, 2, 2, 1, 2, 3), "reading sample 0 of 8"},
+		{"truncated after a chunk", densityFile(1<<16+1, 1, 1, make([]float32, 1<<16)...), "reading sample 65536 of 65537"},
+		{"header claims too many", densityFile(1000, 1000, 1000, 1, 2, 3), "reading sample 0 of 1000000000"},
+		{"negative", densityFile(2, 1, 1, 1, -1), "must be finite and not negative"},
+		{"infinite", densityFile(2, 1, 1, float32(math.Inf(1)), 1), "must be finite and not negative"},
+		{"nan", densityFile(2, 1, 1, 1, float32(math.NaN())), "must be finite and not negative"},
+	}
+	for _, test := range tests {
+		_, err := ReadDensityGrid(bytes.NewReader(test.file), rays.Point{}, rays.Point{X: 1, Y: 1, Z: 1})
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.want)
+		}
+	}
+}
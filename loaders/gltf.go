@@ -0,0 +1,988 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/scene"
+	"github.com/flabbergasted/RayTracer/shapes"
+	"github.com/flabbergasted/RayTracer/textures"
+)
+
+//glb container constants: https://registry.khronos.org/glTF/specs/2.0/glTF-2.0.html#binary-gltf-layout
+const (
+	glbMagic     = 0x46546C67 //"glTF"
+	glbJSONChunk = 0x4E4F534A //"JSON"
+	glbBINChunk  = 0x004E4942 //"BIN\0"
+)
+
+//glTF accessor component types
+const (
+	gltfByte          = 5120
+	gltfUnsignedByte  = 5121
+	gltfShort         = 5122
+	gltfUnsignedShort = 5123
+	gltfUnsignedInt   = 5125
+	gltfFloat         = 5126
+)
+
+//maxGLTFZeroAccessor is the most elements an accessor without a buffer view may have, as its count cannot be checked against any data
+const maxGLTFZeroAccessor = 1 << 24
+
+//glTF primitive modes that describe triangles, the only ones that can become a mesh
+const (
+	gltfTriangles     = 4
+	gltfTriangleStrip = 5
+	gltfTriangleFan   = 6
+)
+
+//glTF sampler values that change how an Image texture is sampled
+const (
+	gltfNearest       = 9728
+	gltfClampToEdge   = 33071
+	gltfMirrorRepeat  = 33648
+	gltfMipmapNearest = 9984 //the first of the four mipmapped minification filters
+	gltfMipmapLinear  = 9987 //the last of the four mipmapped minification filters
+)
+
+//directionalLightDistance is how far back along its direction a directional light is placed, since the renderer only has point lights
+const directionalLightDistance = 1e6
+
+//gltfExtensions lists the extensions the loader understands, any others used by a file are reported as warnings
+var gltfExtensions = map[string]bool{"KHR_lights_punctual": true}
+
+type gltfDocument struct {
+	Asset struct {
+		Version string `json:"version"`
+	} `json:"asset"`
+	ExtensionsUsed     []string         `json:"extensionsUsed"`
+	ExtensionsRequired []string         `json:"extensionsRequired"`
+	Scene              *int             `json:"scene"`
+	Scenes             []gltfScene      `json:"scenes"`
+	Nodes              []gltfNode       `json:"nodes"`
+	Meshes             []gltfMesh       `json:"meshes"`
+	Accessors          []gltfAccessor   `json:"accessors"`
+	BufferViews        []gltfBufferView `json:"bufferViews"`
+	Buffers            []gltfBuffer     `json:"buffers"`
+	Materials          []gltfMaterial   `json:"materials"`
+	Textures           []gltfTexture    `json:"textures"`
+	Images             []gltfImage      `json:"images"`
+	Samplers           []gltfSampler    `json:"samplers"`
+	Cameras            []gltfCamera     `json:"cameras"`
+	Extensions         struct {
+		LightsPunctual struct {
+			Lights []gltfLight `json:"lights"`
+		} `json:"KHR_lights_punctual"`
+	} `json:"extensions"`
+}
+
+type gltfScene struct {
+	Name  string `json:"name"`
+	Nodes []int  `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name        string    `json:"name"`
+	Children    []int     `json:"children"`
+	Mesh        *int      `json:"mesh"`
+	Camera      *int      `json:"camera"`
+	Skin        *int      `json:"skin"`
+	Matrix      []float32 `json:"matrix"`
+	Translation []float32 `json:"translation"`
+	Rotation    []float32 `json:"rotation"`
+	Scale       []float32 `json:"scale"`
+	Extensions  struct {
+		LightsPunctual *struct {
+			Light int `json:"light"`
+		} `json:"KHR_lights_punctual"`
+	} `json:"extensions"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int   `json:"attributes"`
+	Indices    *int             `json:"indices"`
+	Material   *int             `json:"material"`
+	Mode       *int             `json:"mode"`
+	Targets    []map[string]int `json:"targets"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type gltfBuffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type gltfMaterial struct {
+	Name                 string `json:"name"`
+	PBRMetallicRoughness *struct {
+		BaseColorFactor          []float32        `json:"baseColorFactor"`
+		BaseColorTexture         *gltfTextureInfo `json:"baseColorTexture"`
+		MetallicFactor           *float32         `json:"metallicFactor"`
+		RoughnessFactor          *float32         `json:"roughnessFactor"`
+		MetallicRoughnessTexture *gltfTextureInfo `json:"metallicRoughnessTexture"`
+	} `json:"pbrMetallicRoughness"`
+	NormalTexture    *gltfTextureInfo `json:"normalTexture"`
+	OcclusionTexture *gltfTextureInfo `json:"occlusionTexture"`
+	EmissiveTexture  *gltfTextureInfo `json:"emissiveTexture"`
+}
+
+type gltfTextureInfo struct {
+	Index    int `json:"index"`
+	TexCoord int `json:"texCoord"`
+}
+
+type gltfTexture struct {
+	Sampler *int `json:"sampler"`
+	Source  *int `json:"source"`
+}
+
+type gltfImage struct {
+	URI        string `json:"uri"`
+	MimeType   string `json:"mimeType"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type gltfSampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type gltfCamera struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Perspective *struct {
+		YFov float32 `json:"yfov"`
+	} `json:"perspective"`
+}
+
+type gltfLight struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Color     []float32 `json:"color"`
+	Intensity *float32  `json:"intensity"`
+	Range     *float32  `json:"range"`
+}
+
+//gltfLoader holds the state of one file being converted into a scene graph
+type gltfLoader struct {
+	doc      gltfDocument
+	dir      string
+	buffers  [][]byte
+	bitmaps  map[int]*textures.Bitmap
+	warnings []string
+	warned   map[string]bool
+}
+
+//LoadGLTF loads the default scene of a glTF 2.0 file, either a .gltf JSON file with its external or embedded buffers and images, or a binary .glb file.
+//See ReadGLTF for how the file maps onto the scene graph.
+func LoadGLTF(path string) (root *scene.Group, warnings []string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return ReadGLTF(f, filepath.Dir(path))
+}
+
+//ReadGLTF reads a glTF 2.0 file (https://registry.khronos.org/glTF/specs/2.0/glTF-2.0.html) in JSON or binary form, resolving external files relative to dir.
+//The default scene becomes a Group holding one Group per node, with the node's matrix or translation, rotation and scale as its transform.
+//Each triangle primitive of a node's mesh becomes a shapes.Mesh in a ShapeNode, with normals, the first set of texture coordinates and vertex colors
+//kept if present.  Materials keep their base color factor and texture, their normal map and their metallic and roughness factors and
+//texture (see shapes.Mesh for how metal is shown).  Perspective cameras look down their node's -Z axis,
+//and KHR_lights_punctual lights become point lights with their color and intensity.
+//glTF is Y up, so place the root under a Group that flips Y to fit the renderer's Y down scenes.
+//Anything the renderer cannot show, such as unknown extensions, skins, spot light cones or light ranges, is skipped
+//and reported in warnings rather than failing the load.
+func ReadGLTF(r io.Reader, dir string) (root *scene.Group, warnings []string, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var bin []byte
+	if len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic {
+		if data, bin, err = readGLB(data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	l := gltfLoader{dir: dir, bitmaps: map[int]*textures.Bitmap{}, warned: map[string]bool{}}
+	if err := json.Unmarshal(data, &l.doc); err != nil {
+		return nil, nil, fmt.Errorf("gltf: %v", err)
+	}
+	if !strings.HasPrefix(l.doc.Asset.Version, "2.") {
+		return nil, nil, fmt.Errorf("gltf: unsupported version %q, only 2.x files can be loaded", l.doc.Asset.Version)
+	}
+	for _, e := range l.doc.ExtensionsUsed {
+		if !gltfExtensions[e] {
+			l.warn("extension %s is not supported and is ignored", e)
+		}
+	}
+	for _, e := range l.doc.ExtensionsRequired {
+		if !gltfExtensions[e] {
+			l.warn("required extension %s is not supported, parts of the file may be missing", e)
+		}
+	}
+	if err := l.loadBuffers(bin); err != nil {
+		return nil, nil, err
+	}
+
+	root = &scene.Group{Name: "gltf"}
+	var nodes []int
+	switch {
+	case l.doc.Scene != nil:
+		if *l.doc.Scene < 0 || *l.doc.Scene >= len(l.doc.Scenes) {
+			return nil, nil, fmt.Errorf("gltf: scene %d does not exist", *l.doc.Scene)
+		}
+		nodes = l.doc.Scenes[*l.doc.Scene].Nodes
+		root.Name = nameOr(l.doc.Scenes[*l.doc.Scene].Name, root.Name)
+	case len(l.doc.Scenes) > 0:
+		nodes = l.doc.Scenes[0].Nodes
+		root.Name = nameOr(l.doc.Scenes[0].Name, root.Name)
+	}
+	for _, n := range nodes {
+		child, err := l.node(n, map[int]bool{})
+		if err != nil {
+			return nil, nil, err
+		}
+		root.Children = append(root.Children, child)
+	}
+	return root, l.warnings, nil
+}
+
+//readGLB splits a binary glTF file into its JSON chunk and optional binary chunk
+func readGLB(data []byte) (jsonChunk []byte, binChunk []byte, err error) {
+	if len(data) < 12 {
+		return nil, nil, fmt.Errorf("gltf: glb file is truncated")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+		return nil, nil, fmt.Errorf("gltf: unsupported glb version %d", version)
+	}
+	if length := binary.LittleEndian.Uint32(data[8:]); uint64(length) > uint64(len(data)) {
+		return nil, nil, fmt.Errorf("gltf: glb file is truncated, the header says %d bytes but there are %d", length, len(data))
+	}
+
+	for offset := 12; offset < len(data); {
+		if offset+8 > len(data) {
+			return nil, nil, fmt.Errorf("gltf: glb file is truncated in a chunk header")
+		}
+		length := int(binary.LittleEndian.Uint32(data[offset:]))
+		kind := binary.LittleEndian.Uint32(data[offset+4:])
+		start := offset + 8
+		if length < 0 || start+length > len(data) {
+			return nil, nil, fmt.Errorf("gltf: glb file is truncated, a chunk needs %d bytes", length)
+		}
+		switch kind {
+		case glbJSONChunk:
+			jsonChunk = data[start : start+length]
+		case glbBINChunk:
+			binChunk = data[start : start+length]
+		}
+		offset = start + length
+	}
+	if jsonChunk == nil {
+		return nil, nil, fmt.Errorf("gltf: glb file has no JSON chunk")
+	}
+	return jsonChunk, binChunk, nil
+}
+
+//warn records a warning, ignoring repeats so a problem shared by many primitives is only reported once
+func (l *gltfLoader) warn(format string, args ...interface{}) {
+	w := fmt.Sprintf(format, args...)
+	if !l.warned[w] {
+		l.warned[w] = true
+		l.warnings = append(l.warnings, w)
+	}
+}
+
+//loadBuffers reads every buffer, from the glb binary chunk, a data URI or a file next to the glTF file
+func (l *gltfLoader) loadBuffers(bin []byte) error {
+	l.buffers = make([][]byte, len(l.doc.Buffers))
+	for i, b := range l.doc.Buffers {
+		var data []byte
+		var err error
+		if b.URI == "" {
+			if bin == nil {
+				return fmt.Errorf("gltf: buffer %d has no uri and there is no glb binary chunk", i)
+			}
+			data = bin
+		} else if data, err = l.readURI(b.URI); err != nil {
+			return fmt.Errorf("gltf: buffer %d: %v", i, err)
+		}
+		if len(data) < b.ByteLength {
+			return fmt.Errorf("gltf: buffer %d is truncated, it should be %d bytes but is %d", i, b.ByteLength, len(data))
+		}
+		l.buffers[i] = data[:b.ByteLength]
+	}
+	return nil
+}
+
+//readURI returns the contents of a data URI, or of a file relative to the glTF file's directory
+func (l *gltfLoader) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.Index(uri, ",")
+		if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+			return nil, fmt.Errorf("only base64 data uris are supported")
+		}
+		return base64.StdEncoding.DecodeString(uri[comma+1:])
+	}
+	path, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filepath.Join(l.dir, filepath.FromSlash(path)))
+}
+
+//node converts node index and its descendants into a Group.  ancestors holds the nodes above it, to reject cycles.
+func (l *gltfLoader) node(index int, ancestors map[int]bool) (*scene.Group, error) {
+	if index < 0 || index >= len(l.doc.Nodes) {
+		return nil, fmt.Errorf("gltf: node %d does not exist", index)
+	}
+	if ancestors[index] {
+		return nil, fmt.Errorf("gltf: node %d is its own ancestor", index)
+	}
+	ancestors[index] = true
+	defer delete(ancestors, index)
+
+	n := l.doc.Nodes[index]
+	g := &scene.Group{Name: nameOr(n.Name, fmt.Sprintf("node%d", index))}
+	transform, err := nodeTransform(n)
+	if err != nil {
+		return nil, fmt.Errorf("gltf: node %d: %v", index, err)
+	}
+	if transform != rays.Identity() {
+		g.Transform = transform
+	}
+	if n.Skin != nil {
+		l.warn("skins are not supported, skinned meshes are shown in their bind pose")
+	}
+
+	if n.Mesh != nil {
+		shapeNodes, err := l.mesh(*n.Mesh)
+		if err != nil {
+			return nil, err
+		}
+		g.Children = append(g.Children, shapeNodes...)
+	}
+	if n.Camera != nil {
+		camera, err := l.camera(*n.Camera, g.Name)
+		if err != nil {
+			return nil, err
+		}
+		if camera != nil {
+			g.Children = append(g.Children, camera)
+		}
+	}
+	if n.Extensions.LightsPunctual != nil {
+		light, err := l.light(n.Extensions.LightsPunctual.Light, g.Name)
+		if err != nil {
+			return nil, err
+		}
+		g.Children = append(g.Children, light)
+	}
+	for _, c := range n.Children {
+		child, err := l.node(c, ancestors)
+		if err != nil {
+			return nil, err
+		}
+		g.Children = append(g.Children, child)
+	}
+	return g, nil
+}
+
+//nodeTransform returns the node's local transform, from its column major matrix or from its translation, rotation (a unit quaternion) and scale
+func nodeTransform(n gltfNode) (rays.Matrix4, error) {
+	if n.Matrix != nil {
+		if len(n.Matrix) != 16 {
+			return rays.Matrix4{}, fmt.Errorf("matrix has %d values, it needs 16", len(n.Matrix))
+		}
+		var m rays.Matrix4
+		for col := 0; col < 4; col++ {
+			for row := 0; row < 4; row++ {
+				m[row][col] = n.Matrix[col*4+row]
+			}
+		}
+		return m, nil
+	}
+
+	t, s, q := rays.Point{}, rays.Point{X: 1, Y: 1, Z: 1}, [4]float32{0, 0, 0, 1}
+	if n.Translation != nil {
+		if len(n.Translation) != 3 {
+			return rays.Matrix4{}, fmt.Errorf("translation has %d values, it needs 3", len(n.Translation))
+		}
+		t = rays.Point{X: n.Translation[0], Y: n.Translation[1], Z: n.Translation[2]}
+	}
+	if n.Scale != nil {
+		if len(n.Scale) != 3 {
+			return rays.Matrix4{}, fmt.Errorf("scale has %d values, it needs 3", len(n.Scale))
+		}
+		s = rays.Point{X: n.Scale[0], Y: n.Scale[1], Z: n.Scale[2]}
+	}
+	if n.Rotation != nil {
+		if len(n.Rotation) != 4 {
+			return rays.Matrix4{}, fmt.Errorf("rotation has %d values, it needs 4", len(n.Rotation))
+		}
+		copy(q[:], n.Rotation)
+	}
+	return rays.Compose(rays.Scale(s), quaternionMatrix(q), rays.Translate(t)), nil
+}
+
+//quaternionMatrix returns the rotation described by quaternion q, stored as x, y, z, w like glTF does
+func quaternionMatrix(q [4]float32) rays.Matrix4 {
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	if l := float32(math.Sqrt(float64(x*x + y*y + z*z + w*w))); l > 0 {
+		x, y, z, w = x/l, y/l, z/l, w/l
+	}
+	m := rays.Identity()
+	m[0][0], m[0][1], m[0][2] = 1-2*(y*y+z*z), 2*(x*y-z*w), 2*(x*z+y*w)
+	m[1][0], m[1][1], m[1][2] = 2*(x*y+z*w), 1-2*(x*x+z*z), 2*(y*z-x*w)
+	m[2][0], m[2][1], m[2][2] = 2*(x*z-y*w), 2*(y*z+x*w), 1-2*(x*x+y*y)
+	return m
+}
+
+//mesh converts each triangle primitive of mesh index into a ShapeNode holding a shapes.Mesh
+func (l *gltfLoader) mesh(index int) ([]scene.Node, error) {
+	if index < 0 || index >= len(l.doc.Meshes) {
+		return nil, fmt.Errorf("gltf: mesh %d does not exist", index)
+	}
+	gm := l.doc.Meshes[index]
+	name := nameOr(gm.Name, fmt.Sprintf("mesh%d", index))
+
+	var nodes []scene.Node
+	for i, p := range gm.Primitives {
+		m, ok, err := l.primitive(p)
+		if err != nil {
+			return nil, fmt.Errorf("gltf: mesh %q primitive %d: %v", name, i, err)
+		}
+		if ok {
+			nodes = append(nodes, &scene.ShapeNode{Name: fmt.Sprintf("%s#%d", name, i), Shape: m})
+		}
+	}
+	return nodes, nil
+}
+
+//primitive builds a mesh from a primitive's indices and vertex attributes.  Returns false for primitives made of points or lines.
+func (l *gltfLoader) primitive(p gltfPrimitive) (m shapes.Mesh, ok bool, err error) {
+	mode := gltfTriangles
+	if p.Mode != nil {
+		mode = *p.Mode
+	}
+	if mode != gltfTriangles && mode != gltfTriangleStrip && mode != gltfTriangleFan {
+		l.warn("primitive mode %d (points or lines) cannot be rendered and is skipped", mode)
+		return m, false, nil
+	}
+	if len(p.Targets) > 0 {
+		l.warn("morph targets are not supported, meshes are shown without them")
+	}
+
+	position, ok := p.Attributes["POSITION"]
+	if !ok {
+		return m, false, fmt.Errorf("no POSITION attribute")
+	}
+	positions, err := l.accessor(position, "VEC3")
+	if err != nil {
+		return m, false, fmt.Errorf("POSITION: %v", err)
+	}
+	vertices := make([]rays.Point, len(positions))
+	for i, v := range positions {
+		vertices[i] = rays.Point{X: v[0], Y: v[1], Z: v[2]}
+	}
+
+	var indices []int
+	if p.Indices != nil {
+		if indices, err = l.indexAccessor(*p.Indices); err != nil {
+			return m, false, fmt.Errorf("indices: %v", err)
+		}
+		for i, v := range indices {
+			if v < 0 || v >= len(vertices) {
+				return m, false, fmt.Errorf("index %d refers to vertex %d, but there are %d vertices", i, v, len(vertices))
+			}
+		}
+	} else {
+		indices = make([]int, len(vertices))
+		for i := range indices {
+			indices[i] = i
+		}
+	}
+
+	faces := triangles(indices, mode)
+	m = shapes.NewMesh(vertices, faces, rays.Point{X: 1, Y: 1, Z: 1})
+	if index, ok := p.Attributes["NORMAL"]; ok {
+		values, err := l.accessor(index, "VEC3")
+		if err != nil {
+			return m, false, fmt.Errorf("NORMAL: %v", err)
+		}
+		//glTF normals point out of the surface, shapes expect them pointing in
+		m.Normals = make([]rays.Point, len(values))
+		for i, v := range values {
+			m.Normals[i] = rays.Point{X: -v[0], Y: -v[1], Z: -v[2]}
+		}
+	}
+	if index, ok := p.Attributes["TEXCOORD_0"]; ok {
+		values, err := l.accessor(index, "VEC2")
+		if err != nil {
+			return m, false, fmt.Errorf("TEXCOORD_0: %v", err)
+		}
+		m.UVs = make([][2]float32, len(values))
+		for i, v := range values {
+			m.UVs[i] = [2]float32{v[0], v[1]}
+		}
+	}
+	if index, ok := p.Attributes["COLOR_0"]; ok {
+		values, err := l.accessor(index, "VEC3", "VEC4")
+		if err != nil {
+			return m, false, fmt.Errorf("COLOR_0: %v", err)
+		}
+		m.Colors = make([]rays.Point, len(values))
+		for i, v := range values {
+			m.Colors[i] = rays.Point{X: v[0], Y: v[1], Z: v[2]}
+		}
+	}
+	for _, attributes := range [][]rays.Point{m.Normals, m.Colors} {
+		if attributes != nil && len(attributes) != len(vertices) {
+			return m, false, fmt.Errorf("attribute count %d does not match the %d vertices", len(attributes), len(vertices))
+		}
+	}
+	if m.UVs != nil && len(m.UVs) != len(vertices) {
+		return m, false, fmt.Errorf("TEXCOORD_0 count %d does not match the %d vertices", len(m.UVs), len(vertices))
+	}
+
+	if p.Material != nil {
+		if err := l.material(*p.Material, &m); err != nil {
+			return m, false, err
+		}
+	} else if m.Colors != nil {
+		m.Texture = textures.VertexColor{}
+	}
+	return m, true, nil
+}
+
+//triangles splits a list of vertex indices into triangles according to the primitive mode, keeping every triangle counter-clockwise
+func triangles(indices []int, mode int) [][3]int {
+	var faces [][3]int
+	switch mode {
+	case gltfTriangleStrip:
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				faces = append(faces, [3]int{indices[i], indices[i+1], indices[i+2]})
+			} else {
+				faces = append(faces, [3]int{indices[i+1], indices[i], indices[i+2]})
+			}
+		}
+	case gltfTriangleFan:
+		for i := 1; i+1 < len(indices); i++ {
+			faces = append(faces, [3]int{indices[0], indices[i], indices[i+1]})
+		}
+	default:
+		for i := 0; i+2 < len(indices); i += 3 {
+			faces = append(faces, [3]int{indices[i], indices[i+1], indices[i+2]})
+		}
+	}
+	return faces
+}
+
+//material applies material index to mesh m: the base color factor becomes the mesh color, and the base color texture and vertex colors
+//are combined with it into the mesh texture.  The normal texture becomes the normal map.
+func (l *gltfLoader) material(index int, m *shapes.Mesh) error {
+	if index < 0 || index >= len(l.doc.Materials) {
+		return fmt.Errorf("material %d does not exist", index)
+	}
+	gm := l.doc.Materials[index]
+
+	var base textures.Texture
+	if m.Colors != nil {
+		base = textures.VertexColor{}
+	}
+	if pbr := gm.PBRMetallicRoughness; pbr != nil {
+		if pbr.BaseColorFactor != nil {
+			if len(pbr.BaseColorFactor) != 4 {
+				return fmt.Errorf("material %d: baseColorFactor has %d values, it needs 4", index, len(pbr.BaseColorFactor))
+			}
+			m.Color = rays.Point{X: pbr.BaseColorFactor[0], Y: pbr.BaseColorFactor[1], Z: pbr.BaseColorFactor[2]}
+		}
+		if pbr.BaseColorTexture != nil {
+			color, err := l.texture(*pbr.BaseColorTexture, m, "base color texture")
+			if err != nil {
+				return fmt.Errorf("material %d: baseColorTexture: %v", index, err)
+			}
+			if base == nil {
+				base = color
+			} else {
+				base = textures.Modulate{A: base, B: color}
+			}
+		}
+		//both factors default to 1, fully rough metal, which shows its base color as rough metals have no reflection (see shapes.Mesh)
+		m.Metallic, m.Roughness = 1, 1
+		if pbr.MetallicFactor != nil {
+			m.Metallic = *pbr.MetallicFactor
+		}
+		if pbr.RoughnessFactor != nil {
+			m.Roughness = *pbr.RoughnessFactor
+		}
+		if pbr.MetallicRoughnessTexture != nil {
+			metalRough, err := l.texture(*pbr.MetallicRoughnessTexture, m, "metallic-roughness texture")
+			if err != nil {
+				return fmt.Errorf("material %d: metallicRoughnessTexture: %v", index, err)
+			}
+			m.MetallicRoughnessMap = metalRough
+		}
+	}
+	if base != nil {
+		if m.Color != (rays.Point{X: 1, Y: 1, Z: 1}) {
+			base = textures.Modulate{A: base, B: textures.Solid{Color: m.Color}}
+		}
+		m.Texture = base
+	}
+
+	if gm.NormalTexture != nil {
+		normals, err := l.texture(*gm.NormalTexture, m, "normal map")
+		if err != nil {
+			return fmt.Errorf("material %d: normalTexture: %v", index, err)
+		}
+		m.NormalMap = normals
+	}
+	if gm.OcclusionTexture != nil || gm.EmissiveTexture != nil {
+		l.warn("occlusion and emissive textures are not supported and are ignored")
+	}
+	return nil
+}
+
+//texture returns an Image texture for the texture info, sampled as its sampler asks.  Only the first set of texture coordinates is loaded.
+//role names what the texture is used for in warnings.
+func (l *gltfLoader) texture(info gltfTextureInfo, m *shapes.Mesh, role string) (textures.Texture, error) {
+	if info.Index < 0 || info.Index >= len(l.doc.Textures) {
+		return nil, fmt.Errorf("texture %d does not exist", info.Index)
+	}
+	if info.TexCoord != 0 {
+		l.warn("only the first set of texture coordinates is supported, textures using TEXCOORD_%d use TEXCOORD_0", info.TexCoord)
+	}
+	if m.UVs == nil {
+		l.warn("a primitive with a %s has no TEXCOORD_0, so the whole surface reads the same texel of it", role)
+	}
+
+	t := l.doc.Textures[info.Index]
+	if t.Source == nil {
+		return nil, fmt.Errorf("texture %d has no image", info.Index)
+	}
+	bitmap, err := l.bitmap(*t.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	tex := textures.Image{Bitmap: bitmap, Filter: textures.Trilinear}
+	if t.Sampler != nil {
+		if *t.Sampler < 0 || *t.Sampler >= len(l.doc.Samplers) {
+			return nil, fmt.Errorf("sampler %d does not exist", *t.Sampler)
+		}
+		s := l.doc.Samplers[*t.Sampler]
+		if s.MinFilter != 0 && (s.MinFilter < gltfMipmapNearest || s.MinFilter > gltfMipmapLinear) {
+			tex.Filter = textures.Bilinear
+			if s.MagFilter == gltfNearest {
+				tex.Filter = textures.Nearest
+			}
+		}
+		if s.WrapS == gltfClampToEdge || s.WrapT == gltfClampToEdge {
+			tex.Wrap = textures.Clamp
+		}
+		if s.WrapS == gltfMirrorRepeat || s.WrapT == gltfMirrorRepeat {
+			l.warn("mirrored repeat texture wrapping is not supported, textures repeat instead")
+		}
+	}
+	return tex, nil
+}
+
+//bitmap decodes image index, loading each image once however many textures share it
+func (l *gltfLoader) bitmap(index int) (*textures.Bitmap, error) {
+	if b, ok := l.bitmaps[index]; ok {
+		return b, nil
+	}
+	if index < 0 || index >= len(l.doc.Images) {
+		return nil, fmt.Errorf("image %d does not exist", index)
+	}
+
+	gi := l.doc.Images[index]
+	var b *textures.Bitmap
+	var data []byte
+	var err error
+	switch {
+	case gi.BufferView != nil:
+		data, _, err = l.bufferView(*gi.BufferView)
+	case strings.HasPrefix(gi.URI, "data:"):
+		data, err = l.readURI(gi.URI)
+	default:
+		var path string
+		if path, err = url.PathUnescape(gi.URI); err == nil {
+			b, err = textures.LoadBitmap(filepath.Join(l.dir, filepath.FromSlash(path)))
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("image %d: %v", index, err)
+	}
+	if b == nil {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %v", index, err)
+		}
+		if b, err = textures.NewBitmap(img); err != nil {
+			return nil, fmt.Errorf("image %d: %v", index, err)
+		}
+	}
+	l.bitmaps[index] = b
+	return b, nil
+}
+
+//bufferView returns the bytes of buffer view index and its stride, zero if its elements are tightly packed
+func (l *gltfLoader) bufferView(index int) ([]byte, int, error) {
+	if index < 0 || index >= len(l.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("buffer view %d does not exist", index)
+	}
+	v := l.doc.BufferViews[index]
+	if v.Buffer < 0 || v.Buffer >= len(l.buffers) {
+		return nil, 0, fmt.Errorf("buffer view %d refers to buffer %d, which does not exist", index, v.Buffer)
+	}
+	buffer := l.buffers[v.Buffer]
+	if v.ByteOffset < 0 || v.ByteLength < 0 || v.ByteOffset+v.ByteLength > len(buffer) {
+		return nil, 0, fmt.Errorf("buffer view %d runs past the end of buffer %d", index, v.Buffer)
+	}
+	if v.ByteStride < 0 {
+		return nil, 0, fmt.Errorf("buffer view %d has a negative byte stride", index)
+	}
+	return buffer[v.ByteOffset : v.ByteOffset+v.ByteLength], v.ByteStride, nil
+}
+
+//accessor reads accessor index as one row of float values per element, converting normalized integers to [0,1] or [-1,1].
+//The accessor's type must be one of types.
+func (l *gltfLoader) accessor(index int, types ...string) ([][]float32, error) {
+	a, data, stride, components, err := l.accessorLayout(index, types...)
+	if err != nil {
+		return nil, err
+	}
+	values := gltfRows(a.Count, components)
+	if data == nil {
+		return values, nil
+	}
+	size := gltfComponentSize(a.ComponentType)
+	for i := range values {
+		element := data[a.ByteOffset+i*stride:]
+		for c := range values[i] {
+			values[i][c] = gltfComponent(element[c*size:], a.ComponentType, a.Normalized)
+		}
+	}
+	return values, nil
+}
+
+//indexAccessor reads accessor index as vertex indices.  They are decoded as integers rather than through float32, which cannot hold
+//indices past 2^24 exactly.
+func (l *gltfLoader) indexAccessor(index int) ([]int, error) {
+	a, data, stride, _, err := l.accessorLayout(index, "SCALAR")
+	if err != nil {
+		return nil, err
+	}
+	if a.ComponentType != gltfUnsignedByte && a.ComponentType != gltfUnsignedShort && a.ComponentType != gltfUnsignedInt {
+		return nil, fmt.Errorf("accessor %d has component type %d, indices must be unsigned integers", index, a.ComponentType)
+	}
+	indices := make([]int, a.Count)
+	if data == nil {
+		return indices, nil
+	}
+	for i := range indices {
+		element := data[a.ByteOffset+i*stride:]
+		switch a.ComponentType {
+		case gltfUnsignedByte:
+			indices[i] = int(element[0])
+		case gltfUnsignedShort:
+			indices[i] = int(binary.LittleEndian.Uint16(element))
+		default:
+			indices[i] = int(binary.LittleEndian.Uint32(element))
+		}
+	}
+	return indices, nil
+}
+
+//accessorLayout checks accessor index, whose type must be one of types, and returns it with the bytes of its buffer view, the stride
+//between its elements and the number of components in each.  data is nil for an accessor without a buffer view, whose elements are all zero.
+func (l *gltfLoader) accessorLayout(index int, types ...string) (a gltfAccessor, data []byte, stride int, components int, err error) {
+	if index < 0 || index >= len(l.doc.Accessors) {
+		return a, nil, 0, 0, fmt.Errorf("accessor %d does not exist", index)
+	}
+	a = l.doc.Accessors[index]
+	if len(a.Sparse) > 0 {
+		return a, nil, 0, 0, fmt.Errorf("accessor %d: sparse accessors are not supported", index)
+	}
+	typeOK := false
+	for _, t := range types {
+		typeOK = typeOK || a.Type == t
+	}
+	if !typeOK {
+		return a, nil, 0, 0, fmt.Errorf("accessor %d has type %s, expected %s", index, a.Type, strings.Join(types, " or "))
+	}
+
+	components = map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4}[a.Type]
+	size := gltfComponentSize(a.ComponentType)
+	if size == 0 {
+		return a, nil, 0, 0, fmt.Errorf("accessor %d has unknown component type %d", index, a.ComponentType)
+	}
+	if a.Count < 0 {
+		return a, nil, 0, 0, fmt.Errorf("accessor %d has a negative count", index)
+	}
+	if a.BufferView == nil {
+		//an accessor without a buffer view is all zeros, and there is no data to check its count against
+		if a.Count > maxGLTFZeroAccessor {
+			return a, nil, 0, 0, fmt.Errorf("accessor %d has %d elements but no buffer view", index, a.Count)
+		}
+		return a, nil, 0, components, nil
+	}
+
+	data, stride, err = l.bufferView(*a.BufferView)
+	if err != nil {
+		return a, nil, 0, 0, fmt.Errorf("accessor %d: %v", index, err)
+	}
+	if stride == 0 {
+		stride = components * size
+	}
+	//checked by division so huge counts cannot overflow, and before allocating so they fail without exhausting memory
+	if a.Count > 0 && (a.ByteOffset < 0 || a.ByteOffset+components*size > len(data) || (len(data)-a.ByteOffset-components*size)/stride < a.Count-1) {
+		return a, nil, 0, 0, fmt.Errorf("accessor %d needs %d elements, which run past the end of its buffer view", index, a.Count)
+	}
+	return a, data, stride, components, nil
+}
+
+//gltfRows returns count rows of zeros, each holding components values
+func gltfRows(count int, components int) [][]float32 {
+	values := make([][]float32, count)
+	for i := range values {
+		values[i] = make([]float32, components)
+	}
+	return values
+}
+
+//gltfComponentSize returns the size in bytes of a component type, or zero if the type is unknown
+func gltfComponentSize(componentType int) int {
+	switch componentType {
+	case gltfByte, gltfUnsignedByte:
+		return 1
+	case gltfShort, gltfUnsignedShort:
+		return 2
+	case gltfUnsignedInt, gltfFloat:
+		return 4
+	default:
+		return 0
+	}
+}
+
+//gltfComponent decodes one little endian component, scaling normalized integers into [0,1] (unsigned) or [-1,1] (signed)
+func gltfComponent(b []byte, componentType int, normalized bool) float32 {
+	var v, max float32
+	switch componentType {
+	case gltfByte:
+		v, max = float32(int8(b[0])), math.MaxInt8
+	case gltfUnsignedByte:
+		v, max = float32(b[0]), math.MaxUint8
+	case gltfShort:
+		v, max = float32(int16(binary.LittleEndian.Uint16(b))), math.MaxInt16
+	case gltfUnsignedShort:
+		v, max = float32(binary.LittleEndian.Uint16(b)), math.MaxUint16
+	case gltfUnsignedInt:
+		v, max = float32(binary.LittleEndian.Uint32(b)), math.MaxUint32
+	default:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	}
+	if normalized {
+		v = float32(math.Max(float64(v/max), -1))
+	}
+	return v
+}
+
+//camera converts camera index into a scene camera looking down -Z from the node's origin.  Orthographic cameras are skipped with a warning.
+func (l *gltfLoader) camera(index int, nodeName string) (*scene.Camera, error) {
+	if index < 0 || index >= len(l.doc.Cameras) {
+		return nil, fmt.Errorf("gltf: camera %d does not exist", index)
+	}
+	c := l.doc.Cameras[index]
+	if c.Type != "perspective" || c.Perspective == nil {
+		l.warn("%s cameras are not supported and are skipped", c.Type)
+		return nil, nil
+	}
+	return &scene.Camera{
+		Name:        nameOr(c.Name, nodeName),
+		LookAt:      rays.Point{Z: -1},
+		FieldOfView: c.Perspective.YFov,
+	}, nil
+}
+
+//light converts KHR_lights_punctual light index into a point light at the node's origin, keeping its color and intensity.
+//Spot lights lose their cone and every light its range, and directional lights are placed far back along their direction (the node's -Z axis).
+func (l *gltfLoader) light(index int, nodeName string) (*scene.Light, error) {
+	if index < 0 || index >= len(l.doc.Extensions.LightsPunctual.Lights) {
+		return nil, fmt.Errorf("gltf: light %d does not exist", index)
+	}
+	pl := l.doc.Extensions.LightsPunctual.Lights[index]
+	light := &scene.Light{Name: nameOr(pl.Name, nodeName), Color: rays.Point{X: 1, Y: 1, Z: 1}, Intensity: 1}
+	if len(pl.Color) == 3 {
+		light.Color = rays.Point{X: pl.Color[0], Y: pl.Color[1], Z: pl.Color[2]}
+	}
+	if pl.Intensity != nil {
+		if *pl.Intensity < 0 {
+			return nil, fmt.Errorf("gltf: light %d has a negative intensity", index)
+		}
+		light.Intensity = *pl.Intensity
+	}
+	if pl.Range != nil {
+		l.warn("light range is not supported, lights do not fade out with distance")
+	}
+
+	switch pl.Type {
+	case "point":
+	case "spot":
+		l.warn("spot lights are treated as point lights, their cone is ignored")
+	case "directional":
+		l.warn("directional lights are treated as distant point lights")
+		light.Position = rays.Point{Z: directionalLightDistance}
+	default:
+		return nil, fmt.Errorf("gltf: light %d has unknown type %q", index, pl.Type)
+	}
+	return light, nil
+}
+
+//nameOr returns name, or fallback if name is empty
+func nameOr(name string, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	return name
+}
@@ -0,0 +1,112 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/scene"
+)
+
+//testIndexLoader returns a loader holding one SCALAR accessor of componentType over values, written little endian
+func testIndexLoader(componentType int, values interface{}) *gltfLoader {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, values)
+	view := 0
+	l := &gltfLoader{buffers: [][]byte{b.Bytes()}}
+	l.doc.BufferViews = []gltfBufferView{{ByteLength: b.Len()}}
+	l.doc.Accessors = []gltfAccessor{{BufferView: &view, ComponentType: componentType, Count: b.Len() / gltfComponentSize(componentType), Type: "SCALAR"}}
+	return l
+}
+
+func TestGLTFIndexAccessor(t *testing.T) {
+	tests := []struct {
+		name          string
+		componentType int
+		values        interface{}
+		want          []int
+	}{
+		{"bytes", gltfUnsignedByte, []uint8{0, 7, 255}, []int{0, 7, 255}},
+		{"shorts", gltfUnsignedShort, []uint16{1, 65535}, []int{1, 65535}},
+		//float32 rounds 2^24+1 down to 2^24
+		{"ints past 2^24", gltfUnsignedInt, []uint32{1<<24 + 1, 1<<31 + 3}, []int{1<<24 + 1, 1<<31 + 3}},
+	}
+	for _, test := range tests {
+		got, err := testIndexLoader(test.componentType, test.values).indexAccessor(0)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+				break
+			}
+		}
+	}
+
+	if _, err := testIndexLoader(gltfFloat, []float32{1, 2, 3}).indexAccessor(0); err == nil {
+		t.Error("float indices were accepted")
+	}
+}
+
+func TestGLTFLightIntensity(t *testing.T) {
+	doc := `{"asset": {"version": "2.0"}, "scene": 0, "scenes": [{"nodes": [0, 1]}],
+		"nodes": [{"extensions": {"KHR_lights_punctual": {"light": 0}}}, {"extensions": {"KHR_lights_punctual": {"light": 1}}}],
+		"extensions": {"KHR_lights_punctual": {"lights": [{"type": "point", "intensity": 40, "range": 10}, {"type": "point"}]}}}`
+	root, warnings, err := ReadGLTF(strings.NewReader(doc), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := scene.Flatten(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Lights) != 2 || s.Lights[0].Intensity != 40 || s.Lights[1].Intensity != 1 {
+		t.Errorf("lights = %v, want intensities 40 and the default of 1", s.Lights)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "range") {
+		t.Errorf("warnings = %v, want one about the light's range", warnings)
+	}
+
+	negative := strings.Replace(doc, `"intensity": 40`, `"intensity": -1`, 1)
+	if _, _, err := ReadGLTF(strings.NewReader(negative), "."); err == nil {
+		t.Error("a negative light intensity was accepted")
+	}
+}
+
+func TestGLTFMissingTexCoordWarning(t *testing.T) {
+	var positions, pixels bytes.Buffer
+	binary.Write(&positions, binary.LittleEndian, []float32{0, 0, 0, 1, 0, 0, 0, 1, 0})
+	png.Encode(&pixels, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	doc := fmt.Sprintf(`{"asset": {"version": "2.0"}, "scene": 0, "scenes": [{"nodes": [0]}], "nodes": [{"mesh": 0}],
+		"meshes": [{"primitives": [{"attributes": {"POSITION": 0}, "material": 0}]}],
+		"materials": [{"normalTexture": {"index": 0}, "pbrMetallicRoughness": {"metallicRoughnessTexture": {"index": 0}}}],
+		"textures": [{"source": 0}], "images": [{"uri": "data:image/png;base64,%s"}],
+		"accessors": [{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"}],
+		"bufferViews": [{"buffer": 0, "byteLength": 36}], "buffers": [{"uri": "data:application/octet-stream;base64,%s", "byteLength": 36}]}`,
+		base64.StdEncoding.EncodeToString(pixels.Bytes()), base64.StdEncoding.EncodeToString(positions.Bytes()))
+	_, warnings, err := ReadGLTF(strings.NewReader(doc), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//one warning for each texture, naming what it is used for
+	want := []string{"metallic-roughness texture", "normal map"}
+	if len(warnings) != len(want) {
+		t.Fatalf("warnings = %v, want one for each of %v", warnings, want)
+	}
+	for i, role := range want {
+		if !strings.Contains(warnings[i], role) || !strings.Contains(warnings[i], "TEXCOORD_0") {
+			t.Errorf("warning %q does not name the %s and the missing TEXCOORD_0", warnings[i], role)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package loaders
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/shapes"
+	"github.com/flabbergasted/RayTracer/textures"
+)
+
+//plyProperty is one property of a PLY element.  List properties have a countType, holding the number of items that follow.
+type plyProperty struct {
+	name      string
+	valueType string
+	countType string
+}
+
+//plyElement is an element declared in a PLY header, such as vertex or face
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+//maxPLYListLength is the most items a list property may hold, such as the corners of a face
+const maxPLYListLength = 1 << 16
+
+//maxPLYPreallocate caps how many items are allocated up front from an element count in the header, so a corrupt count fails when the data
+//runs out instead of exhausting memory first
+const maxPLYPreallocate = 1 << 16
+
+//plyValueReader reads the values of a PLY body, one at a time, as text or binary numbers
+type plyValueReader interface {
+	read(valueType string) (float64, error)
+}
+
+//LoadPLY loads a triangle mesh from a PLY file in ASCII or binary (little or big endian) format.
+//See ReadPLY for the properties that are understood.
+func LoadPLY(path string, color rays.Point) (shapes.Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return shapes.Mesh{}, err
+	}
+	defer f.Close()
+	return ReadPLY(f, color)
+}
+
+//ReadPLY reads a triangle mesh in PLY format: http://paulbourke.net/dataformats/ply/
+//Vertex positions (x, y, z) are required.  Normals (nx, ny, nz), colors (red, green, blue) and texture coordinates (u, v or s, t) are kept if present,
+//and vertex colors are shown through a textures.VertexColor texture.  Faces with more than three vertices are split into a fan of triangles,
+//and other elements are skipped.
+func ReadPLY(r io.Reader, color rays.Point) (shapes.Mesh, error) {
+	data, err := readPLY(r)
+	if err != nil {
+		return shapes.Mesh{}, err
+	}
+
+	m := shapes.NewMesh(data.vertices, data.faces, color)
+	m.Normals, m.Colors, m.UVs = data.normals, data.colors, data.uvs
+	if data.colors != nil {
+		m.Texture = textures.VertexColor{}
+	}
+	return m, nil
+}
+
+//LoadPLYPoints loads the vertices of a PLY file as a point cloud.  See ReadPLYPoints for the properties that are understood.
+func LoadPLYPoints(path string, radius float32, color rays.Point) (shapes.PointCloud, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return shapes.PointCloud{}, err
+	}
+	defer f.Close()
+	return ReadPLYPoints(f, radius, color)
+}
+
+//ReadPLYPoints reads the vertices of a PLY file as a point cloud, the way LiDAR scans are often stored.  Colors (red, green, blue) and a radius
+//per point are kept if present, points without their own radius use radius.  Faces and other elements are skipped.
+func ReadPLYPoints(r io.Reader, radius float32, color rays.Point) (shapes.PointCloud, error) {
+	data, err := readPLY(r)
+	if err != nil {
+		return shapes.PointCloud{}, err
+	}
+
+	radii := data.radii
+	if radii == nil {
+		radii = []float32{radius}
+	}
+	pc, err := shapes.NewPointCloud(data.vertices, radii, color)
+	if err != nil {
+		return shapes.PointCloud{}, fmt.Errorf("ply: %v", err)
+	}
+	return withPointColors(pc, data.colors), nil
+}
+
+//plyData is the vertex and face data read from a PLY file.  Optional vertex properties are nil if the file does not have them.
+type plyData struct {
+	vertices []rays.Point
+	normals  []rays.Point
+	colors   []rays.Point
+	uvs      [][2]float32
+	radii    []float32
+	faces    [][3]int
+}
+
+//readPLY reads the header and body of a PLY file, keeping the vertex and face elements
+func readPLY(r io.Reader) (plyData, error) {
+	br := bufio.NewReader(r)
+	format, elements, err := readPLYHeader(br)
+	if err != nil {
+		return plyData{}, err
+	}
+
+	var values plyValueReader
+	switch format {
+	case "ascii":
+		scanner := bufio.NewScanner(br)
+		scanner.Split(bufio.ScanWords)
+		values = &plyTextReader{scanner: scanner}
+	case "binary_little_endian":
+		values = plyBinaryReader{r: br, order: binary.LittleEndian}
+	case "binary_big_endian":
+		values = plyBinaryReader{r: br, order: binary.BigEndian}
+	default:
+		return plyData{}, fmt.Errorf("ply: unsupported format %q", format)
+	}
+
+	var data plyData
+	for _, e := range elements {
+		switch e.name {
+		case "vertex":
+			err = readPLYVertices(values, e, &data)
+		case "face":
+			data.faces, err = readPLYFaces(values, e, len(data.vertices))
+		default:
+			err = skipPLYElement(values, e)
+		}
+		if err != nil {
+			return plyData{}, err
+		}
+	}
+	if len(data.vertices) == 0 {
+		return plyData{}, fmt.Errorf("ply: no vertices")
+	}
+	return data, nil
+}
+
+//readPLYHeader reads the header up to end_header, returning the body format and the declared elements in order
+func readPLYHeader(br *bufio.Reader) (format string, elements []plyElement, err error) {
+	line, err := br.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ply" {
+		return "", nil, fmt.Errorf("ply: missing ply magic number")
+	}
+
+	for lineNumber := 2; ; lineNumber++ {
+		line, err = br.ReadString('\n')
+		if err != nil {
+			return "", nil, fmt.Errorf("ply: header ends before end_header")
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "end_header":
+			if format == "" {
+				return "", nil, fmt.Errorf("ply: header has no format line")
+			}
+			return format, elements, nil
+		case "comment", "obj_info":
+		case "format":
+			if len(fields) != 3 {
+				return "", nil, fmt.Errorf("ply: line %d: malformed format line", lineNumber)
+			}
+			format = fields[1]
+		case "element":
+			if len(fields) != 3 {
+				return "", nil, fmt.Errorf("ply: line %d: malformed element line", lineNumber)
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 {
+				return "", nil, fmt.Errorf("ply: line %d: bad element count %q", lineNumber, fields[2])
+			}
+			elements = append(elements, plyElement{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return "", nil, fmt.Errorf("ply: line %d: property before any element", lineNumber)
+			}
+			var p plyProperty
+			switch {
+			case len(fields) == 5 && fields[1] == "list":
+				p = plyProperty{countType: fields[2], valueType: fields[3], name: fields[4]}
+			case len(fields) == 3:
+				p = plyProperty{valueType: fields[1], name: fields[2]}
+			default:
+				return "", nil, fmt.Errorf("ply: line %d: malformed property line", lineNumber)
+			}
+			if plyTypeSize(p.valueType) == 0 || (p.countType != "" && plyTypeSize(p.countType) == 0) {
+				return "", nil, fmt.Errorf("ply: line %d: unknown property type", lineNumber)
+			}
+			e := &elements[len(elements)-1]
+			e.properties = append(e.properties, p)
+		default:
+			return "", nil, fmt.Errorf("ply: line %d: unexpected header keyword %q", lineNumber, fields[0])
+		}
+	}
+}
+
+//readPLYVertices reads the vertex element into data, keeping positions and whichever of normals, colors, UVs and radii it has
+func readPLYVertices(values plyValueReader, e plyElement, data *plyData) error {
+	index := map[string]int{}
+	for i, p := range e.properties {
+		index[p.name] = i
+	}
+	has := func(names ...string) bool {
+		for _, n := range names {
+			if _, ok := index[n]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	if !has("x", "y", "z") {
+		return fmt.Errorf("ply: vertex element has no x, y and z properties")
+	}
+	uName, vName := "u", "v"
+	if !has(uName, vName) {
+		uName, vName = "s", "t"
+	}
+
+	capacity := plyCapacity(e.count)
+	data.vertices = make([]rays.Point, 0, capacity)
+	if has("nx", "ny", "nz") {
+		data.normals = make([]rays.Point, 0, capacity)
+	}
+	if has("red", "green", "blue") {
+		data.colors = make([]rays.Point, 0, capacity)
+	}
+	if has(uName, vName) {
+		data.uvs = make([][2]float32, 0, capacity)
+	}
+	if has("radius") {
+		data.radii = make([]float32, 0, capacity)
+	}
+
+	row := make([]float64, len(e.properties))
+	for i := 0; i < e.count; i++ {
+		for j, p := range e.properties {
+			if p.countType != "" {
+				if err := skipPLYList(values, p); err != nil {
+					return fmt.Errorf("ply: vertex %d: %v", i, err)
+				}
+				continue
+			}
+			var err error
+			if row[j], err = values.read(p.valueType); err != nil {
+				return fmt.Errorf("ply: vertex %d: %v", i, err)
+			}
+		}
+
+		data.vertices = append(data.vertices, plyPoint(row, index, "x", "y", "z", 1))
+		if data.normals != nil {
+			//PLY normals point out of the surface, shapes expect them pointing in
+			data.normals = append(data.normals, rays.Multiply(plyPoint(row, index, "nx", "ny", "nz", 1), -1))
+		}
+		if data.colors != nil {
+			data.colors = append(data.colors, plyPoint(row, index, "red", "green", "blue", plyColorScale(e.properties[index["red"]].valueType)))
+		}
+		if data.uvs != nil {
+			data.uvs = append(data.uvs, [2]float32{float32(row[index[uName]]), float32(row[index[vName]])})
+		}
+		if data.radii != nil {
+			data.radii = append(data.radii, float32(row[index["radius"]]))
+		}
+	}
+	return nil
+}
+
+//readPLYFaces reads the face element, splitting each polygon into a fan of triangles and checking its indices against vertexCount
+func readPLYFaces(values plyValueReader, e plyElement, vertexCount int) ([][3]int, error) {
+	faces := make([][3]int, 0, plyCapacity(e.count))
+	for i := 0; i < e.count; i++ {
+		for _, p := range e.properties {
+			if p.countType == "" || (p.name != "vertex_indices" && p.name != "vertex_index") {
+				if err := skipPLYProperty(values, p); err != nil {
+					return nil, fmt.Errorf("ply: face %d: %v", i, err)
+				}
+				continue
+			}
+
+			n, err := values.read(p.countType)
+			if err != nil {
+				return nil, fmt.Errorf("ply: face %d: %v", i, err)
+			}
+			if !plyInteger(n) || n < 3 || n > maxPLYListLength {
+				return nil, fmt.Errorf("ply: face %d has %v vertices, from 3 to %d are supported", i, n, maxPLYListLength)
+			}
+			polygon := make([]int, int(n))
+			for j := range polygon {
+				v, err := values.read(p.valueType)
+				if err != nil {
+					return nil, fmt.Errorf("ply: face %d: %v", i, err)
+				}
+				if !plyInteger(v) {
+					return nil, fmt.Errorf("ply: face %d has vertex index %v, which is not a whole number", i, v)
+				}
+				if v < 0 || v >= float64(vertexCount) {
+					return nil, fmt.Errorf("ply: face %d refers to vertex %v, but there are %d vertices", i, v, vertexCount)
+				}
+				polygon[j] = int(v)
+			}
+			for j := 1; j+1 < len(polygon); j++ {
+				faces = append(faces, [3]int{polygon[0], polygon[j], polygon[j+1]})
+			}
+		}
+	}
+	return faces, nil
+}
+
+//skipPLYElement reads and discards every instance of an element the mesh does not use
+func skipPLYElement(values plyValueReader, e plyElement) error {
+	for i := 0; i < e.count; i++ {
+		for _, p := range e.properties {
+			if err := skipPLYProperty(values, p); err != nil {
+				return fmt.Errorf("ply: %s %d: %v", e.name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func skipPLYProperty(values plyValueReader, p plyProperty) error {
+	if p.countType != "" {
+		return skipPLYList(values, p)
+	}
+	_, err := values.read(p.valueType)
+	return err
+}
+
+func skipPLYList(values plyValueReader, p plyProperty) error {
+	n, err := values.read(p.countType)
+	if err != nil {
+		return err
+	}
+	if !plyInteger(n) || n < 0 || n > maxPLYListLength {
+		return fmt.Errorf("bad list length %v", n)
+	}
+	for i := 0; i < int(n); i++ {
+		if _, err := values.read(p.valueType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//plyInteger returns true if v is a whole number, as counts and indices must be.  ASCII bodies can hold fractions, nan and inf.
+func plyInteger(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0) && v == math.Trunc(v)
+}
+
+//plyCapacity returns how many items to allocate up front for an element with count instances
+func plyCapacity(count int) int {
+	if count > maxPLYPreallocate {
+		return maxPLYPreallocate
+	}
+	return count
+}
+
+//plyPoint builds a point from three named values of a row, each multiplied by scale
+func plyPoint(row []float64, index map[string]int, x string, y string, z string, scale float64) rays.Point {
+	return rays.Point{X: float32(row[index[x]] * scale), Y: float32(row[index[y]] * scale), Z: float32(row[index[z]] * scale)}
+}
+
+//plyColorScale returns the factor that maps color values of the given type into [0,1].  Integer colors use their full range, floats are already in [0,1].
+func plyColorScale(valueType string) float64 {
+	switch valueType {
+	case "uchar", "uint8", "char", "int8":
+		return 1.0 / 255
+	case "ushort", "uint16", "short", "int16":
+		return 1.0 / 65535
+	case "uint", "uint32", "int", "int32":
+		return 1.0 / math.MaxUint32
+	default:
+		return 1
+	}
+}
+
+//plyTypeSize returns the size in bytes of a PLY value type, or 0 if it is not a known type
+func plyTypeSize(valueType string) int {
+	switch valueType {
+	case "char", "int8", "uchar", "uint8":
+		return 1
+	case "short", "int16", "ushort", "uint16":
+		return 2
+	case "int", "int32", "uint", "uint32", "float", "float32":
+		return 4
+	case "double", "float64":
+		return 8
+	default:
+		return 0
+	}
+}
+
+//plyTextReader reads the whitespace separated values of an ASCII PLY body
+type plyTextReader struct {
+	scanner *bufio.Scanner
+}
+
+func (t *plyTextReader) read(valueType string) (float64, error) {
+	if !t.scanner.Scan() {
+		if err := t.scanner.Err(); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("file is truncated")
+	}
+	v, err := strconv.ParseFloat(t.scanner.Text(), 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad %s value %q", valueType, t.scanner.Text())
+	}
+	return v, nil
+}
+
+//plyBinaryReader reads the packed values of a binary PLY body
+type plyBinaryReader struct {
+	r     io.Reader
+	order binary.ByteOrder
+}
+
+func (b plyBinaryReader) read(valueType string) (float64, error) {
+	var buf [8]byte
+	size := plyTypeSize(valueType)
+	if _, err := io.ReadFull(b.r, buf[:size]); err != nil {
+		return 0, fmt.Errorf("file is truncated")
+	}
+	switch valueType {
+	case "char", "int8":
+		return float64(int8(buf[0])), nil
+	case "uchar", "uint8":
+		return float64(buf[0]), nil
+	case "short", "int16":
+		return float64(int16(b.order.Uint16(buf[:]))), nil
+	case "ushort", "uint16":
+		return float64(b.order.Uint16(buf[:])), nil
+	case "int", "int32":
+		return float64(int32(b.order.Uint32(buf[:]))), nil
+	case "uint", "uint32":
+		return float64(b.order.Uint32(buf[:])), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(b.order.Uint32(buf[:]))), nil
+	default:
+		return math.Float64frombits(b.order.Uint64(buf[:])), nil
+	}
+}
@@ -0,0 +1,101 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//testPLYHeader declares 4 colored vertices and 1 face, in format
+func testPLYHeader(format string) string {
+	return "ply\nformat " + format + " 1.0\ncomment a unit square\nelement vertex 4\nproperty float x\nproperty float y\nproperty float z\n" +
+		"property uchar red\nproperty uchar green\nproperty uchar blue\nelement face 1\nproperty list uchar int vertex_indices\nend_header\n"
+}
+
+const testPLYBody = "0 0 0 255 0 0\n1 0 0 0 255 0\n1 1 0 0 0 255\n0 1 0 255 255 255\n4 0 1 2 3\n"
+
+//binaryPLY returns the square of testPLYBody in a binary format, with face given as its vertex count then its indices
+func binaryPLY(order binary.ByteOrder, face ...int32) []byte {
+	name := map[binary.ByteOrder]string{binary.LittleEndian: "binary_little_endian", binary.BigEndian: "binary_big_endian"}[order]
+	var b bytes.Buffer
+	b.WriteString(testPLYHeader(name))
+	corners := [][3]float32{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}
+	colors := [][3]uint8{{255, 0, 0}, {0, 255, 0}, {0, 0, 255}, {255, 255, 255}}
+	for i := range corners {
+		binary.Write(&b, order, corners[i])
+		binary.Write(&b, order, colors[i])
+	}
+	if len(face) > 0 {
+		b.WriteByte(byte(face[0]))
+		binary.Write(&b, order, face[1:])
+	}
+	return b.Bytes()
+}
+
+func TestReadPLY(t *testing.T) {
+	files := map[string][]byte{
+		"ascii":                []byte(testPLYHeader("ascii") + testPLYBody),
+		"binary little endian": binaryPLY(binary.LittleEndian, 4, 0, 1, 2, 3),
+		"binary big endian":    binaryPLY(binary.BigEndian, 4, 0, 1, 2, 3),
+	}
+	for name, file := range files {
+		m, err := ReadPLY(bytes.NewReader(file), rays.Point{})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := fmt.Sprint(m.Vertices); got != "[{0 0 0} {1 0 0} {1 1 0} {0 1 0}]" {
+			t.Errorf("%s: vertices = %s", name, got)
+		}
+		//the quad is split into a fan
+		if got := fmt.Sprint(m.Faces); got != "[[0 1 2] [0 2 3]]" {
+			t.Errorf("%s: faces = %s", name, got)
+		}
+		if got := fmt.Sprint(m.Colors); got != "[{1 0 0} {0 1 0} {0 0 1} {1 1 1}]" || m.Texture == nil {
+			t.Errorf("%s: colors = %s shown by %v", name, got, m.Texture)
+		}
+	}
+}
+
+func TestReadPLYErrors(t *testing.T) {
+	ascii := func(body string) []byte {
+		return []byte(testPLYHeader("ascii") + body)
+	}
+	vertices := testPLYBody[:strings.Index(testPLYBody, "4 0 1 2 3")]
+	tests := []struct {
+		name string
+		file []byte
+		want string
+	}{
+		{"no magic", []byte("plx\nformat ascii 1.0\nend_header\n"), "missing ply magic number"},
+		{"no end_header", []byte("ply\nformat ascii 1.0\nelement vertex 1\n"), "header ends before end_header"},
+		{"unknown format", []byte(testPLYHeader("binary_middle_endian")), `unsupported format "binary_middle_endian"`},
+		{"negative element count", []byte("ply\nformat ascii 1.0\nelement vertex -3\nend_header\n"), `line 3: bad element count "-3"`},
+		{"unknown type", []byte("ply\nformat ascii 1.0\nelement vertex 1\nproperty float128 x\nend_header\n"), "line 4: unknown property type"},
+		{"no vertices", []byte("ply\nformat ascii 1.0\nelement vertex 0\nproperty float x\nproperty float y\nproperty float z\nend_header\n"), "no vertices"},
+		{"no positions", []byte("ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nend_header\n1\n"), "no x, y and z properties"},
+		{"truncated ascii vertex", ascii(testPLYBody[:20]), "vertex 1: file is truncated"},
+		{"truncated ascii face", ascii(vertices + "4 0 1"), "face 0: file is truncated"},
+		{"bad ascii value", ascii(strings.Replace(testPLYBody, "1 1 0", "1 one 0", 1)), `vertex 2: bad float value "one"`},
+		{"truncated little endian", binaryPLY(binary.LittleEndian, 4, 0, 1), "face 0: file is truncated"},
+		{"truncated big endian", binaryPLY(binary.BigEndian)[:len(testPLYHeader("binary_big_endian"))+30], "vertex 2: file is truncated"},
+		{"fractional count", ascii(vertices + "3.5 0 1 2\n"), "face 0 has 3.5 vertices"},
+		{"nan count", ascii(vertices + "nan 0 1 2\n"), "face 0 has NaN vertices"},
+		{"two corners", ascii(vertices + "2 0 1\n"), "face 0 has 2 vertices, from 3"},
+		{"fractional index", ascii(vertices + "3 0 1.5 2\n"), "face 0 has vertex index 1.5, which is not a whole number"},
+		{"infinite index", ascii(vertices + "3 0 inf 2\n"), "face 0 has vertex index +Inf"},
+		{"index past the end", ascii(vertices + "3 0 1 4\n"), "face 0 refers to vertex 4, but there are 4 vertices"},
+		{"negative index", binaryPLY(binary.LittleEndian, 3, 0, -1, 2), "face 0 refers to vertex -1"},
+		{"big endian index", binaryPLY(binary.BigEndian, 3, 0, 1, 1<<24), "face 0 refers to vertex 1.6777216e+07"},
+	}
+	for _, test := range tests {
+		_, err := ReadPLY(bytes.NewReader(test.file), rays.Point{})
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/shapes"
+	"github.com/flabbergasted/RayTracer/textures"
+)
+
+//stlHeaderSize is the size of a binary STL file's header and triangle count, and stlTriangleSize the size of each triangle record
+const (
+	stlHeaderSize   = 84
+	stlTriangleSize = 50
+)
+
+//stlColorValid is set in a binary STL triangle's attribute word when it holds a color, in the VisCAM and SolidView convention
+const stlColorValid = 1 << 15
+
+//LoadSTL loads a triangle mesh from an STL file in ASCII or binary format
+func LoadSTL(path string, color rays.Point) (shapes.Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return shapes.Mesh{}, err
+	}
+	defer f.Close()
+	return ReadSTL(f, color)
+}
+
+//ReadSTL reads a triangle mesh in STL format: https://en.wikipedia.org/wiki/STL_(file_format)
+//Binary files are recognised by their size matching their triangle count, since some binary files also begin with "solid".
+//STL stores every triangle with its own three vertices, so vertices shared between triangles are repeated.  The stored facet normals are ignored.
+//Binary files that color their triangles the way VisCAM and SolidView do, 5 bits each of red, green and blue in the attribute word with bit 15
+//set, keep the colors as vertex colors shown through a textures.VertexColor texture, and triangles without one use color.  Materialise Magics'
+//colors, which clear bit 15 instead, are not recognised.
+func ReadSTL(r io.Reader, color rays.Point) (shapes.Mesh, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return shapes.Mesh{}, err
+	}
+
+	var vertices, colors []rays.Point
+	if isBinarySTL(data) {
+		vertices, colors, err = readBinarySTL(data, color)
+	} else {
+		vertices, err = readTextSTL(data)
+	}
+	if err != nil {
+		return shapes.Mesh{}, err
+	}
+
+	faces := make([][3]int, len(vertices)/3)
+	for i := range faces {
+		faces[i] = [3]int{3 * i, 3*i + 1, 3*i + 2}
+	}
+	m := shapes.NewMesh(vertices, faces, color)
+	if colors != nil {
+		m.Colors, m.Texture = colors, textures.VertexColor{}
+	}
+	return m, nil
+}
+
+//isBinarySTL returns true if data looks like a binary STL file, either because its size matches the triangle count in its header or because it
+//does not read like an ASCII file, which starts with "solid" and has facet or endsolid keywords
+func isBinarySTL(data []byte) bool {
+	if len(data) >= stlHeaderSize {
+		count := binary.LittleEndian.Uint32(data[80:84])
+		if uint64(len(data)) == stlHeaderSize+uint64(count)*stlTriangleSize {
+			return true
+		}
+	}
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("solid")) {
+		return true
+	}
+	return !bytes.Contains(data, []byte("facet")) && !bytes.Contains(data, []byte("endsolid"))
+}
+
+//readBinarySTL returns the three vertices of every triangle in a binary STL file, and a color for each vertex if any triangle has one, where
+//triangles without a color use color
+func readBinarySTL(data []byte, color rays.Point) (vertices []rays.Point, colors []rays.Point, err error) {
+	if len(data) < stlHeaderSize {
+		return nil, nil, fmt.Errorf("stl: file is truncated, it is shorter than the binary header")
+	}
+	count := int(binary.LittleEndian.Uint32(data[80:84]))
+	if body := len(data) - stlHeaderSize; body < count*stlTriangleSize {
+		return nil, nil, fmt.Errorf("stl: file is truncated, the header says %d triangles but there is only room for %d", count, body/stlTriangleSize)
+	}
+
+	vertices, colors = make([]rays.Point, 0, 3*count), make([]rays.Point, 0, 3*count)
+	colored := false
+	for i := 0; i < count; i++ {
+		record := data[stlHeaderSize+i*stlTriangleSize:]
+		//skip the 12 byte facet normal, the vertices follow as 3 float32s each
+		for v := 0; v < 3; v++ {
+			offset := 12 + 12*v
+			vertices = append(vertices, rays.Point{
+				X: math.Float32frombits(binary.LittleEndian.Uint32(record[offset:])),
+				Y: math.Float32frombits(binary.LittleEndian.Uint32(record[offset+4:])),
+				Z: math.Float32frombits(binary.LittleEndian.Uint32(record[offset+8:]))})
+		}
+		c := color
+		if attribute := binary.LittleEndian.Uint16(record[48:]); attribute&stlColorValid != 0 {
+			c, colored = stlColor(attribute), true
+		}
+		colors = append(colors, c, c, c)
+	}
+	if !colored {
+		colors = nil
+	}
+	return vertices, colors, nil
+}
+
+//stlColor decodes the color in a binary STL triangle's attribute word, 5 bits each of red, green and blue with blue in the lowest bits
+func stlColor(attribute uint16) rays.Point {
+	channel := func(shift uint) float32 {
+		return float32(attribute>>shift&0x1f) / 31
+	}
+	return rays.Point{X: channel(10), Y: channel(5), Z: channel(0)}
+}
+
+//readTextSTL returns the three vertices of every facet in an ASCII STL file
+func readTextSTL(data []byte) ([]rays.Point, error) {
+	var vertices []rays.Point
+	inFacet, facetVertices, ended := false, 0, false
+
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		lineNumber := i + 1
+
+		switch fields[0] {
+		case "solid", "outer", "endloop":
+		case "facet":
+			if inFacet {
+				return nil, fmt.Errorf("stl: line %d: facet starts before the previous one ends", lineNumber)
+			}
+			inFacet, facetVertices = true, 0
+		case "vertex":
+			if !inFacet {
+				return nil, fmt.Errorf("stl: line %d: vertex outside a facet", lineNumber)
+			}
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("stl: line %d: vertex needs 3 coordinates", lineNumber)
+			}
+			var c [3]float32
+			for j := range c {
+				v, err := strconv.ParseFloat(fields[j+1], 32)
+				if err != nil {
+					return nil, fmt.Errorf("stl: line %d: bad coordinate %q", lineNumber, fields[j+1])
+				}
+				c[j] = float32(v)
+			}
+			vertices = append(vertices, rays.Point{X: c[0], Y: c[1], Z: c[2]})
+			facetVertices++
+		case "endfacet":
+			if !inFacet || facetVertices != 3 {
+				return nil, fmt.Errorf("stl: line %d: facet has %d vertices, it must have 3", lineNumber, facetVertices)
+			}
+			inFacet = false
+		case "endsolid":
+			ended = true
+		default:
+			return nil, fmt.Errorf("stl: line %d: unexpected keyword %q", lineNumber, fields[0])
+		}
+	}
+
+	if inFacet || !ended {
+		return nil, fmt.Errorf("stl: file is truncated, it ends before endsolid")
+	}
+	return vertices, nil
+}
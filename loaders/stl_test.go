@@ -0,0 +1,109 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+const testTextSTL = `solid test
+facet normal 0 0 1
+ outer loop
+  vertex 0 0 0
+  vertex 1 0 0
+  vertex 0 1 0
+ endloop
+endfacet
+facet normal 0 0 1
+ outer loop
+  vertex 1 0 0
+  vertex 1 1 0
+  vertex 0 1 0
+ endloop
+endfacet
+endsolid test
+`
+
+//binarySTL returns a binary STL file holding one triangle for each attribute word, each a unit triangle moved i along x
+func binarySTL(attributes ...uint16) []byte {
+	var b bytes.Buffer
+	header := make([]byte, 80)
+	copy(header, "solid but binary")
+	b.Write(header)
+	binary.Write(&b, binary.LittleEndian, uint32(len(attributes)))
+	for i, a := range attributes {
+		x := float32(i)
+		binary.Write(&b, binary.LittleEndian, []float32{0, 0, 1, x, 0, 0, x + 1, 0, 0, x, 1, 0})
+		binary.Write(&b, binary.LittleEndian, a)
+	}
+	return b.Bytes()
+}
+
+func TestReadSTL(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  []byte
+		faces int
+	}{
+		{"text", []byte(testTextSTL), 2},
+		{"binary", binarySTL(0, 0, 0), 3},
+		{"empty binary", binarySTL(), 0},
+	}
+	for _, test := range tests {
+		m, err := ReadSTL(bytes.NewReader(test.file), rays.Point{X: 1})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(m.Faces) != test.faces || len(m.Vertices) != 3*test.faces {
+			t.Errorf("%s: %d faces and %d vertices, want %d and %d", test.name, len(m.Faces), len(m.Vertices), test.faces, 3*test.faces)
+		}
+		if m.Colors != nil || m.Texture != nil {
+			t.Errorf("%s: uncolored file has vertex colors", test.name)
+		}
+	}
+}
+
+func TestReadSTLColors(t *testing.T) {
+	//full red, then half green and full blue, then a triangle with no color
+	m, err := ReadSTL(bytes.NewReader(binarySTL(stlColorValid|0x1f<<10, stlColorValid|0x0f<<5|0x1f, 0x7fff)), rays.Point{X: 0.5, Y: 0.5, Z: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rays.Point{{X: 1}, {Y: 15.0 / 31, Z: 1}, {X: 0.5, Y: 0.5, Z: 0.5}}
+	if len(m.Colors) != 9 || m.Texture == nil {
+		t.Fatalf("got %d vertex colors and texture %v, want 9 shown by a texture", len(m.Colors), m.Texture)
+	}
+	for i, c := range m.Colors {
+		if c != want[i/3] {
+			t.Errorf("vertex %d color = %v, want %v", i, c, want[i/3])
+		}
+	}
+}
+
+func TestReadSTLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+		want string
+	}{
+		{"truncated binary", binarySTL(0, 0)[:stlHeaderSize+60], "header says 2 triangles but there is only room for 1"},
+		{"short binary header", []byte("not solid"), "shorter than the binary header"},
+		{"truncated text", []byte(testTextSTL[:strings.Index(testTextSTL, "endsolid")]), "ends before endsolid"},
+		{"bad coordinate", []byte(strings.Replace(testTextSTL, "vertex 1 1 0", "vertex 1 x 0", 1)), `line 12: bad coordinate "x"`},
+		{"two coordinates", []byte(strings.Replace(testTextSTL, "vertex 1 1 0", "vertex 1 1", 1)), "line 12: vertex needs 3 coordinates"},
+		{"missing vertex", []byte(strings.Replace(testTextSTL, "  vertex 1 1 0\n", "", 1)), "line 14: facet has 2 vertices"},
+		{"nested facet", []byte(strings.Replace(testTextSTL, "endfacet\nfacet", "facet", 1)), "line 8: facet starts before the previous one ends"},
+		{"stray vertex", []byte(strings.Replace(testTextSTL, "endfacet\nendsolid", "endfacet\nvertex 0 0 0\nendsolid", 1)), "vertex outside a facet"},
+		{"unknown keyword", []byte(strings.Replace(testTextSTL, "outer loop", "inner loop", 1)), `line 3: unexpected keyword "inner"`},
+	}
+	for _, test := range tests {
+		_, err := ReadSTL(bytes.NewReader(test.file), rays.Point{})
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.want)
+		}
+	}
+}
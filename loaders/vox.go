@@ -0,0 +1,157 @@
+package loaders
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/shapes"
+)
+
+//maxVOXSize is the most voxels a MagicaVoxel model can have along each axis
+const maxVOXSize = 256
+
+//LoadVOX loads voxel art from a MagicaVoxel .vox file.  See ReadVOX for how the voxels are placed.
+func LoadVOX(path string, origin rays.Point, voxelSize float32) (shapes.VoxelGrid, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return shapes.VoxelGrid{}, err
+	}
+	defer f.Close()
+	return ReadVOX(f, origin, voxelSize)
+}
+
+//ReadVOX reads voxel art in MagicaVoxel's .vox format: https://github.com/ephtracy/voxel-model/blob/master/MagicaVoxel-file-format-vox.txt
+//The SIZE and XYZI chunks of the first model give its voxels and the RGBA chunk its palette, or MagicaVoxel's default palette if there is none.
+//Other models and chunks, such as the scene graph and materials, are skipped.  MagicaVoxel's Z axis points up, so voxel x, y, z is placed at
+//x, sizeZ-1-z, y in the grid, putting the top of the model towards -Y (up in the default scene).  Models that fill less than an eighth of
+//their size are stored sparsely.
+func ReadVOX(r io.Reader, origin rays.Point, voxelSize float32) (shapes.VoxelGrid, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return shapes.VoxelGrid{}, err
+	}
+	if len(data) < 8 || string(data[:4]) != "VOX " {
+		return shapes.VoxelGrid{}, fmt.Errorf("vox: not a MagicaVoxel file")
+	}
+
+	id, _, children, _, err := readVOXChunk(data[8:])
+	if err != nil {
+		return shapes.VoxelGrid{}, err
+	}
+	if id != "MAIN" {
+		return shapes.VoxelGrid{}, fmt.Errorf("vox: file starts with a %q chunk instead of MAIN", id)
+	}
+
+	var size [3]int
+	var voxels []byte
+	palette := defaultVOXPalette()
+	for len(children) > 0 {
+		id, content, _, rest, err := readVOXChunk(children)
+		if err != nil {
+			return shapes.VoxelGrid{}, err
+		}
+		children = rest
+
+		switch id {
+		case "SIZE":
+			if size != [3]int{} {
+				//only the first model is loaded
+				continue
+			}
+			if len(content) < 12 {
+				return shapes.VoxelGrid{}, fmt.Errorf("vox: SIZE chunk is too short")
+			}
+			for i := range size {
+				size[i] = int(int32(binary.LittleEndian.Uint32(content[i*4:])))
+			}
+			//voxel coordinates are single bytes, so no model is larger than that
+			if size[0] < 1 || size[0] > maxVOXSize || size[1] < 1 || size[1] > maxVOXSize || size[2] < 1 || size[2] > maxVOXSize {
+				return shapes.VoxelGrid{}, fmt.Errorf("vox: model size %dx%dx%d is not between 1 and %d along each axis", size[0], size[1], size[2], maxVOXSize)
+			}
+		case "XYZI":
+			if voxels != nil {
+				continue
+			}
+			if len(content) < 4 {
+				return shapes.VoxelGrid{}, fmt.Errorf("vox: XYZI chunk is too short")
+			}
+			count := int(binary.LittleEndian.Uint32(content))
+			if count < 0 || len(content) < 4+count*4 {
+				return shapes.VoxelGrid{}, fmt.Errorf("vox: XYZI chunk is too short for %d voxels", count)
+			}
+			voxels = content[4 : 4+count*4]
+		case "RGBA":
+			if len(content) < 256*4 {
+				return shapes.VoxelGrid{}, fmt.Errorf("vox: RGBA chunk is too short")
+			}
+			//entry i of the chunk colors palette index i+1
+			for i := 0; i < 255; i++ {
+				c := content[i*4:]
+				palette[i+1] = rays.Point{X: float32(c[0]) / 255, Y: float32(c[1]) / 255, Z: float32(c[2]) / 255}
+			}
+		}
+	}
+	if voxels == nil {
+		return shapes.VoxelGrid{}, fmt.Errorf("vox: no XYZI chunk")
+	}
+
+	//MagicaVoxel's x, y, z become grid x, z, y, with its z flipped so up is -Y
+	newGrid := shapes.NewVoxelGrid
+	if len(voxels)/4*8 < size[0]*size[1]*size[2] {
+		newGrid = shapes.NewSparseVoxelGrid
+	}
+	grid, err := newGrid(size[0], size[2], size[1], origin, voxelSize, rays.Point{X: 1, Y: 1, Z: 1})
+	if err != nil {
+		return shapes.VoxelGrid{}, fmt.Errorf("vox: %v", err)
+	}
+	grid.Palette = palette
+	for i := 0; i < len(voxels); i += 4 {
+		x, y, z, index := int(voxels[i]), int(voxels[i+1]), int(voxels[i+2]), voxels[i+3]
+		if err := grid.Set(x, size[2]-1-z, y, index); err != nil {
+			return shapes.VoxelGrid{}, fmt.Errorf("vox: voxel %d: %v", i/4, err)
+		}
+	}
+	return grid, nil
+}
+
+//readVOXChunk reads the chunk at the start of data, returning its id, its content, the bytes of its children and the data after it
+func readVOXChunk(data []byte) (id string, content []byte, children []byte, rest []byte, err error) {
+	if len(data) < 12 {
+		return "", nil, nil, nil, fmt.Errorf("vox: chunk header is truncated")
+	}
+	id = string(data[:4])
+	contentSize, childrenSize := binary.LittleEndian.Uint32(data[4:]), binary.LittleEndian.Uint32(data[8:])
+	end := 12 + uint64(contentSize) + uint64(childrenSize)
+	if end > uint64(len(data)) {
+		return "", nil, nil, nil, fmt.Errorf("vox: %q chunk is truncated", id)
+	}
+	content = data[12 : 12+contentSize]
+	return id, content, data[12+contentSize : end], data[end:], nil
+}
+
+//defaultVOXPalette returns the palette MagicaVoxel uses for files without an RGBA chunk: a 6x6x6 color cube from white down to (but not
+//including) black, followed by ramps of red, green, blue and gray.  Index 0 is empty.
+func defaultVOXPalette() []rays.Point {
+	palette := make([]rays.Point, 1, 256)
+	cube := []float32{0xff, 0xcc, 0x99, 0x66, 0x33, 0x00}
+	for _, r := range cube {
+		for _, g := range cube {
+			for _, b := range cube {
+				palette = append(palette, rays.Point{X: r / 255, Y: g / 255, Z: b / 255})
+			}
+		}
+	}
+	palette = palette[:216]
+
+	ramp := []float32{0xee, 0xdd, 0xbb, 0xaa, 0x88, 0x77, 0x55, 0x44, 0x22, 0x11}
+	for _, channel := range []rays.Point{{X: 1}, {Y: 1}, {Z: 1}, {X: 1, Y: 1, Z: 1}} {
+		for _, level := range ramp {
+			palette = append(palette, rays.Multiply(channel, level/255))
+		}
+	}
+	return palette
+}
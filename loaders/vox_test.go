@@ -0,0 +1,89 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//voxChunk returns a chunk with the given content and children
+func voxChunk(id string, content []byte, children ...[]byte) []byte {
+	var all []byte
+	for _, c := range children {
+		all = append(all, c...)
+	}
+	var b bytes.Buffer
+	b.WriteString(id)
+	binary.Write(&b, binary.LittleEndian, [2]uint32{uint32(len(content)), uint32(len(all))})
+	b.Write(content)
+	b.Write(all)
+	return b.Bytes()
+}
+
+//voxFile returns a .vox file whose MAIN chunk holds chunks
+func voxFile(chunks ...[]byte) []byte {
+	return append([]byte{'V', 'O', 'X', ' ', 150, 0, 0, 0}, voxChunk("MAIN", nil, chunks...)...)
+}
+
+//voxSize returns a SIZE chunk
+func voxSize(x uint32, y uint32, z uint32) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, [3]uint32{x, y, z})
+	return voxChunk("SIZE", b.Bytes())
+}
+
+//voxXYZI returns an XYZI chunk holding voxels, each x, y, z and palette index
+func voxXYZI(voxels ...[4]byte) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, uint32(len(voxels)))
+	for _, v := range voxels {
+		b.Write(v[:])
+	}
+	return voxChunk("XYZI", b.Bytes())
+}
+
+func TestReadVOX(t *testing.T) {
+	file := voxFile(voxSize(2, 3, 4), voxXYZI([4]byte{1, 2, 0, 7}, [4]byte{0, 0, 3, 9}))
+	grid, err := ReadVOX(bytes.NewReader(file), rays.Point{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, y, z := grid.Size(); x != 2 || y != 4 || z != 3 {
+		t.Errorf("Size() = %d, %d, %d, want 2, 4, 3", x, y, z)
+	}
+	//MagicaVoxel's z is up, so it becomes the flipped grid y
+	if got := grid.Voxel(1, 3, 2); got != 7 {
+		t.Errorf("voxel 1 3 2 = %d, want 7", got)
+	}
+	if got := grid.Voxel(0, 0, 0); got != 9 {
+		t.Errorf("voxel 0 0 0 = %d, want 9", got)
+	}
+}
+
+func TestReadVOXErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+		want string
+	}{
+		{"not vox", []byte("PLY 150 0 0 0"), "not a MagicaVoxel file"},
+		{"truncated main", voxFile(voxSize(1, 1, 1))[:20], "chunk is truncated"},
+		{"no voxels", voxFile(voxSize(1, 1, 1)), "no XYZI chunk"},
+		{"short size", voxFile(voxChunk("SIZE", []byte{1, 0, 0, 0}), voxXYZI()), "SIZE chunk is too short"},
+		{"zero size", voxFile(voxSize(0, 1, 1), voxXYZI()), "model size 0x1x1"},
+		{"size past 256", voxFile(voxSize(257, 1, 1), voxXYZI()), "model size 257x1x1"},
+		{"huge size", voxFile(voxSize(2147483647, 2147483647, 4), voxXYZI()), "model size 2147483647x2147483647x4"},
+		{"negative size", voxFile(voxSize(0xffffffff, 1, 1), voxXYZI()), "model size -1x1x1"},
+		{"short voxels", voxFile(voxSize(1, 1, 1), voxChunk("XYZI", []byte{2, 0, 0, 0, 0, 0, 0, 1})), "too short for 2 voxels"},
+		{"voxel outside", voxFile(voxSize(2, 2, 2), voxXYZI([4]byte{2, 0, 0, 1})), "voxel 0: voxel 2 1 0 is outside"},
+	}
+	for _, test := range tests {
+		_, err := ReadVOX(bytes.NewReader(test.file), rays.Point{}, 1)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.want)
+		}
+	}
+}
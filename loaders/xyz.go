@@ -0,0 +1,102 @@
+package loaders
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/shapes"
+	"github.com/flabbergasted/RayTracer/textures"
+)
+
+//LoadXYZ loads a point cloud from an XYZ text file, the plain format most LiDAR tools can export.  See ReadXYZ for the columns understood.
+func LoadXYZ(path string, radius float32, color rays.Point) (shapes.PointCloud, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return shapes.PointCloud{}, err
+	}
+	defer f.Close()
+	return ReadXYZ(f, radius, color)
+}
+
+//ReadXYZ reads a point cloud with one point per line: x y z, optionally followed by a red green blue color and then a radius per point.
+//Values may be separated by spaces, tabs or commas.  Colors may be given from 0 to 1 or from 0 to 255, and are taken as 0 to 255 if any of them
+//is above 1.  Points without their own radius use radius.  Blank lines and lines starting with # or // are skipped.
+func ReadXYZ(r io.Reader, radius float32, color rays.Point) (shapes.PointCloud, error) {
+	var points, colors []rays.Point
+	var radii []float32
+	columns, maxColor := 0, float32(0)
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(c rune) bool {
+			return c == ' ' || c == '\t' || c == ','
+		})
+		if columns == 0 {
+			columns = len(fields)
+			if columns != 3 && columns != 6 && columns != 7 {
+				return shapes.PointCloud{}, fmt.Errorf("xyz: line %d: expected 3, 6 or 7 values, found %d", lineNumber, columns)
+			}
+		} else if len(fields) != columns {
+			return shapes.PointCloud{}, fmt.Errorf("xyz: line %d: expected %d values like the first point, found %d", lineNumber, columns, len(fields))
+		}
+
+		var v [7]float32
+		for i, field := range fields {
+			f, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return shapes.PointCloud{}, fmt.Errorf("xyz: line %d: bad number %q", lineNumber, field)
+			}
+			v[i] = float32(f)
+		}
+		points = append(points, rays.Point{X: v[0], Y: v[1], Z: v[2]})
+		if columns >= 6 {
+			colors = append(colors, rays.Point{X: v[3], Y: v[4], Z: v[5]})
+			for _, c := range v[3:6] {
+				if c > maxColor {
+					maxColor = c
+				}
+			}
+		}
+		if columns == 7 {
+			radii = append(radii, v[6])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return shapes.PointCloud{}, fmt.Errorf("xyz: %v", err)
+	}
+	if len(points) == 0 {
+		return shapes.PointCloud{}, fmt.Errorf("xyz: no points")
+	}
+
+	if maxColor > 1 {
+		for i := range colors {
+			colors[i] = rays.Multiply(colors[i], 1.0/255)
+		}
+	}
+	if radii == nil {
+		radii = []float32{radius}
+	}
+	pc, err := shapes.NewPointCloud(points, radii, color)
+	if err != nil {
+		return shapes.PointCloud{}, fmt.Errorf("xyz: %v", err)
+	}
+	return withPointColors(pc, colors), nil
+}
+
+//withPointColors gives the cloud its per point colors, if it has any, shown through a textures.VertexColor texture
+func withPointColors(pc shapes.PointCloud, colors []rays.Point) shapes.PointCloud {
+	if colors != nil {
+		pc.Colors = colors
+		pc.Texture = textures.VertexColor{}
+	}
+	return pc
+}
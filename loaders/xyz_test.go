@@ -0,0 +1,61 @@
+package loaders
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+func TestReadXYZ(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		points string
+		colors string
+		radii  string
+	}{
+		{"positions", "# scan\n1 2 3\n\n4 5 6\n", "[{1 2 3} {4 5 6}]", "[]", "[0.5]"},
+		{"commas and tabs", "1,2,3\n4\t5\t6\n", "[{1 2 3} {4 5 6}]", "[]", "[0.5]"},
+		{"unit colors", "1 2 3 1 0 0.5\n", "[{1 2 3}]", "[{1 0 0.5}]", "[0.5]"},
+		{"byte colors", "// rgb\n1 2 3 255 0 0\n4 5 6 0 1 0\n", "[{1 2 3} {4 5 6}]", "[{1 0 0} {0 0.003921569 0}]", "[0.5]"},
+		{"radii", "1 2 3 0 0 0 0.25\n4 5 6 0 0 0 2\n", "[{1 2 3} {4 5 6}]", "[{0 0 0} {0 0 0}]", "[0.25 2]"},
+	}
+	for _, test := range tests {
+		pc, err := ReadXYZ(strings.NewReader(test.file), 0.5, rays.Point{})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := fmt.Sprint(pc.Points); got != test.points {
+			t.Errorf("%s: points = %s, want %s", test.name, got, test.points)
+		}
+		if got := fmt.Sprint(pc.Colors); got != test.colors {
+			t.Errorf("%s: colors = %s, want %s", test.name, got, test.colors)
+		}
+		if got := fmt.Sprint(pc.Radii); got != test.radii {
+			t.Errorf("%s: radii = %s, want %s", test.name, got, test.radii)
+		}
+	}
+}
+
+func TestReadXYZErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"empty", "# nothing here\n", "no points"},
+		{"two values", "1 2\n", "line 1: expected 3, 6 or 7 values, found 2"},
+		{"four values", "1 2 3 4\n", "line 1: expected 3, 6 or 7 values, found 4"},
+		{"ragged", "1 2 3\n\n4 5 6 1 1 1\n", "line 3: expected 3 values like the first point, found 6"},
+		{"bad number", "1 2 3\n4 five 6\n", `line 2: bad number "five"`},
+	}
+	for _, test := range tests {
+		_, err := ReadXYZ(strings.NewReader(test.file), 1, rays.Point{})
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package rays
+
+//RayDifferential is a ray travelling with two auxiliary rays offset by one pixel in screen x and y.
+//Where the three rays land on a surface gives the footprint the pixel covers there, which textures use to pick a filter size.
+//See: http://www.pbr-book.org/3ed-2018/Texture/Sampling_and_Antialiasing.html
+type RayDifferential struct {
+	Ray
+	RxOrigin         Point
+	RxDirection      Point
+	RyOrigin         Point
+	RyDirection      Point
+	HasDifferentials bool
+}
+
+//NewCameraRayDifferential returns the ray from the camera through pixelPoint, with differentials through the neighbouring pixels one unit along x and y
+func NewCameraRayDifferential(cameraPosition Point, pixelPoint Point) RayDifferential {
+	return RayDifferential{
+		Ray:              Ray{Origin: cameraPosition, Direction: Normalize(cameraPosition, pixelPoint)},
+		RxOrigin:         cameraPosition,
+		RxDirection:      Normalize(cameraPosition, Point{X: pixelPoint.X + 1, Y: pixelPoint.Y, Z: pixelPoint.Z}),
+		RyOrigin:         cameraPosition,
+		RyDirection:      Normalize(cameraPosition, Point{X: pixelPoint.X, Y: pixelPoint.Y + 1, Z: pixelPoint.Z}),
+		HasDifferentials: true,
+	}
+}
+
+//Transfer returns the points where the auxiliary rays cross the tangent plane at p with surface normal n.
+//ok is false if the ray has no differentials or an auxiliary ray runs parallel to the plane.
+func (rd RayDifferential) Transfer(p Point, n Point) (px Point, py Point, ok bool) {
+	if !rd.HasDifferentials {
+		return px, py, false
+	}
+	dx := DotProduct(n, rd.RxDirection)
+	dy := DotProduct(n, rd.RyDirection)
+	if dx == 0 || dy == 0 {
+		return px, py, false
+	}
+
+	tx := DotProduct(n, Subtract(p, rd.RxOrigin)) / dx
+	ty := DotProduct(n, Subtract(p, rd.RyOrigin)) / dy
+	px = Add(rd.RxOrigin, Multiply(rd.RxDirection, tx))
+	py = Add(rd.RyOrigin, Multiply(rd.RyDirection, ty))
+	return px, py, true
+}
+
+//Reflect returns the differential reflected off a surface at p.  The main ray reflects about surfaceNormal, each auxiliary ray
+//starts where it crossed the surface and reflects about the normal there (nx and ny), which carries the surface's curvature along.
+func (rd RayDifferential) Reflect(surfaceNormal Ray, px Point, nx Point, py Point, ny Point) RayDifferential {
+	res := RayDifferential{Ray: RayFromAngle(surfaceNormal, rd.Ray), HasDifferentials: rd.HasDifferentials}
+	if !rd.HasDifferentials {
+		return res
+	}
+
+	res.RxOrigin = px
+	res.RxDirection = RayFromAngle(Ray{Direction: nx}, Ray{Direction: rd.RxDirection}).Direction
+	res.RyOrigin = py
+	res.RyDirection = RayFromAngle(Ray{Direction: ny}, Ray{Direction: rd.RyDirection}).Direction
+	return res
+}
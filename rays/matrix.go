@@ -0,0 +1,166 @@
+package rays
+
+import (
+	"errors"
+	"math"
+)
+
+//Matrix4 is a 4x4 affine transform stored as rows, applied to points as column vectors (p' = M * p)
+type Matrix4 [4][4]float32
+
+//Identity returns the transform that leaves everything where it is
+func Identity() Matrix4 {
+	return Matrix4{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1}}
+}
+
+//Translate returns a transform that moves points by t
+func Translate(t Point) Matrix4 {
+	m := Identity()
+	m[0][3], m[1][3], m[2][3] = t.X, t.Y, t.Z
+	return m
+}
+
+//Scale returns a transform that scales each axis by the matching component of s
+func Scale(s Point) Matrix4 {
+	m := Identity()
+	m[0][0], m[1][1], m[2][2] = s.X, s.Y, s.Z
+	return m
+}
+
+//RotateX returns a transform that rotates around the X axis by the given number of radians
+func RotateX(radians float32) Matrix4 {
+	return Rotate(Point{X: 1}, radians)
+}
+
+//RotateY returns a transform that rotates around the Y axis by the given number of radians
+func RotateY(radians float32) Matrix4 {
+	return Rotate(Point{Y: 1}, radians)
+}
+
+//RotateZ returns a transform that rotates around the Z axis by the given number of radians
+func RotateZ(radians float32) Matrix4 {
+	return Rotate(Point{Z: 1}, radians)
+}
+
+//Rotate returns a transform that rotates around axis (through the origin) by the given number of radians, using Rodrigues' rotation formula
+func Rotate(axis Point, radians float32) Matrix4 {
+	a := Normalize(Point{}, axis)
+	s, c := math.Sincos(float64(radians))
+	sin, cos := float32(s), float32(c)
+	t := 1 - cos
+
+	m := Identity()
+	m[0][0] = t*a.X*a.X + cos
+	m[0][1] = t*a.X*a.Y - sin*a.Z
+	m[0][2] = t*a.X*a.Z + sin*a.Y
+	m[1][0] = t*a.X*a.Y + sin*a.Z
+	m[1][1] = t*a.Y*a.Y + cos
+	m[1][2] = t*a.Y*a.Z - sin*a.X
+	m[2][0] = t*a.X*a.Z - sin*a.Y
+	m[2][1] = t*a.Y*a.Z + sin*a.X
+	m[2][2] = t*a.Z*a.Z + cos
+	return m
+}
+
+//Compose returns a single transform that applies each of transforms in the order given, so Compose(Scale(s), Translate(t)) scales then translates
+func Compose(transforms ...Matrix4) Matrix4 {
+	res := Identity()
+	for _, m := range transforms {
+		res = m.Multiply(res)
+	}
+	return res
+}
+
+//Multiply returns the matrix product m * n, which applies n first and then m
+func (m Matrix4) Multiply(n Matrix4) Matrix4 {
+	var res Matrix4
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			res[i][j] = m[i][0]*n[0][j] + m[i][1]*n[1][j] + m[i][2]*n[2][j] + m[i][3]*n[3][j]
+		}
+	}
+	return res
+}
+
+//Transpose returns m with its rows and columns swapped
+func (m Matrix4) Transpose() Matrix4 {
+	var res Matrix4
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			res[i][j] = m[j][i]
+		}
+	}
+	return res
+}
+
+//Inverse returns the transform that undoes m, computed by Gauss-Jordan elimination in float64 to limit rounding error
+func (m Matrix4) Inverse() (Matrix4, error) {
+	var a [4][8]float64
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			a[i][j] = float64(m[i][j])
+		}
+		a[i][4+i] = 1
+	}
+
+	for col := 0; col < 4; col++ {
+		//partial pivoting, swap in the row with the largest value in this column
+		pivot := col
+		for row := col + 1; row < 4; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return Matrix4{}, errors.New("matrix is not invertible")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+
+		scale := 1 / a[col][col]
+		for j := 0; j < 8; j++ {
+			a[col][j] *= scale
+		}
+		for row := 0; row < 4; row++ {
+			if row == col {
+				continue
+			}
+			f := a[row][col]
+			for j := 0; j < 8; j++ {
+				a[row][j] -= f * a[col][j]
+			}
+		}
+	}
+
+	var res Matrix4
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			res[i][j] = float32(a[i][4+j])
+		}
+	}
+	return res, nil
+}
+
+//TransformPoint returns p moved by the transform, including translation
+func (m Matrix4) TransformPoint(p Point) Point {
+	return Point{
+		X: m[0][0]*p.X + m[0][1]*p.Y + m[0][2]*p.Z + m[0][3],
+		Y: m[1][0]*p.X + m[1][1]*p.Y + m[1][2]*p.Z + m[1][3],
+		Z: m[2][0]*p.X + m[2][1]*p.Y + m[2][2]*p.Z + m[2][3]}
+}
+
+//TransformDirection returns direction d rotated and scaled by the transform, ignoring translation.  The result is not normalized.
+func (m Matrix4) TransformDirection(d Point) Point {
+	return Point{
+		X: m[0][0]*d.X + m[0][1]*d.Y + m[0][2]*d.Z,
+		Y: m[1][0]*d.X + m[1][1]*d.Y + m[1][2]*d.Z,
+		Z: m[2][0]*d.X + m[2][1]*d.Y + m[2][2]*d.Z}
+}
+
+//TransformRay returns r with its origin and direction transformed.  The direction is not normalized, so distances along it are preserved.
+func (m Matrix4) TransformRay(r Ray) Ray {
+	return Ray{Origin: m.TransformPoint(r.Origin), Direction: m.TransformDirection(r.Direction)}
+}
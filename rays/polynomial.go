@@ -0,0 +1,154 @@
+package rays
+
+import (
+	"math"
+	"sort"
+)
+
+//polishIterations is the number of Newton steps used to refine each root found in closed form
+const polishIterations = 4
+
+//SolveQuadratic returns the real roots of a*x^2 + b*x + c = 0 in ascending order.  A repeated root is returned once.
+//Discriminants that are negative only by rounding error are treated as zero, so grazing rays still find their tangent point.
+func SolveQuadratic(a float64, b float64, c float64) []float64 {
+	if a == 0 {
+		if b == 0 {
+			return nil
+		}
+		return []float64{-c / b}
+	}
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		if disc < -1e-12*math.Max(b*b, math.Abs(4*a*c)) {
+			return nil
+		}
+		disc = 0
+	}
+	if disc == 0 {
+		return []float64{-b / (2 * a)}
+	}
+
+	//avoid cancellation by computing the larger magnitude root first
+	q := -0.5 * (b + math.Copysign(math.Sqrt(disc), b))
+	x0, x1 := q/a, c/q
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	return []float64{x0, x1}
+}
+
+//SolveCubic returns the real roots of a*x^3 + b*x^2 + c*x + d = 0 in ascending order, using the trigonometric method when there are three
+//See: https://en.wikipedia.org/wiki/Cubic_equation#Trigonometric_and_hyperbolic_solutions
+func SolveCubic(a float64, b float64, c float64, d float64) []float64 {
+	if a == 0 {
+		return SolveQuadratic(b, c, d)
+	}
+	A, B, C := b/a, c/a, d/a
+	q := (A*A - 3*B) / 9
+	r := (2*A*A*A - 9*A*B + 27*C) / 54
+	offset := A / 3
+
+	var res []float64
+	if q3 := q * q * q; r*r < q3 {
+		theta := math.Acos(r / math.Sqrt(q3))
+		sq := -2 * math.Sqrt(q)
+		res = []float64{
+			sq*math.Cos(theta/3) - offset,
+			sq*math.Cos((theta+2*math.Pi)/3) - offset,
+			sq*math.Cos((theta-2*math.Pi)/3) - offset}
+	} else {
+		u := -math.Copysign(math.Cbrt(math.Abs(r)+math.Sqrt(r*r-q3)), r)
+		v := 0.0
+		if u != 0 {
+			v = q / u
+		}
+		res = []float64{u + v - offset}
+		//a discriminant of zero, to within rounding, also has a double root where the other two meet
+		if r*r-q3 <= 1e-12*math.Max(r*r, math.Abs(q3)) && u != 0 {
+			res = append(res, -(u+v)/2-offset)
+		}
+	}
+
+	coeffs := []float64{a, b, c, d}
+	for i := range res {
+		res[i] = polishRoot(coeffs, res[i])
+	}
+	sort.Float64s(res)
+	return res
+}
+
+//SolveQuartic returns the real roots of a*x^4 + b*x^3 + c*x^2 + d*x + e = 0 in ascending order.
+//The quartic is factored into two quadratics with Ferrari's method, then each root is polished with Newton's method against the
+//original polynomial to recover the precision lost in the factoring.  Shapes bounded by quartic surfaces (a torus for example) should
+//move the ray origin close to the surface before building the coefficients, since the roots lose precision as they grow.
+//See: https://en.wikipedia.org/wiki/Quartic_function#Ferrari's_solution
+func SolveQuartic(a float64, b float64, c float64, d float64, e float64) []float64 {
+	if a == 0 {
+		return SolveCubic(b, c, d, e)
+	}
+	B, C, D, E := b/a, c/a, d/a, e/a
+
+	//substitute x = y - B/4 to get the depressed quartic y^4 + p*y^2 + q*y + r
+	B2 := B * B
+	p := C - 3*B2/8
+	q := D - B*C/2 + B2*B/8
+	r := E - B*D/4 + B2*C/16 - 3*B2*B2/256
+	offset := B / 4
+
+	var ys []float64
+	if math.Abs(q) <= 1e-12*math.Max(math.Pow(math.Abs(p), 1.5), math.Pow(math.Abs(r), 0.75)) {
+		//biquadratic (q is negligible next to the other terms at the scale of the roots), solve for y^2
+		for _, z := range SolveQuadratic(1, p, r) {
+			if z >= 0 {
+				s := math.Sqrt(z)
+				ys = append(ys, -s, s)
+			}
+		}
+	} else {
+		//the resolvent cubic always has a positive root since it is -q^2 at zero, the largest is the best conditioned
+		zs := SolveCubic(1, 2*p, p*p-4*r, -q*q)
+		z := zs[len(zs)-1]
+		if z <= 0 {
+			return nil
+		}
+		s := math.Sqrt(z)
+		ys = append(ys, SolveQuadratic(1, s, (p+z-q/s)/2)...)
+		ys = append(ys, SolveQuadratic(1, -s, (p+z+q/s)/2)...)
+	}
+
+	coeffs := []float64{a, b, c, d, e}
+	res := make([]float64, 0, len(ys))
+	for _, y := range ys {
+		res = append(res, polishRoot(coeffs, y-offset))
+	}
+	sort.Float64s(res)
+	return res
+}
+
+//polishRoot refines root x of the polynomial with the given coefficients (highest power first) with Newton's method,
+//keeping each step only if it brings the polynomial closer to zero
+func polishRoot(coeffs []float64, x float64) float64 {
+	fx, _ := evaluatePolynomial(coeffs, x)
+	for i := 0; i < polishIterations && fx != 0; i++ {
+		_, dfx := evaluatePolynomial(coeffs, x)
+		if dfx == 0 {
+			break
+		}
+		next := x - fx/dfx
+		fnext, _ := evaluatePolynomial(coeffs, next)
+		if math.Abs(fnext) >= math.Abs(fx) {
+			break
+		}
+		x, fx = next, fnext
+	}
+	return x
+}
+
+//evaluatePolynomial returns the value and derivative at x of the polynomial with the given coefficients (highest power first), by Horner's rule
+func evaluatePolynomial(coeffs []float64, x float64) (value float64, derivative float64) {
+	for _, c := range coeffs {
+		derivative = derivative*x + value
+		value = value*x + c
+	}
+	return value, derivative
+}
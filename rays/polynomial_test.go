@@ -0,0 +1,103 @@
+package rays
+
+import (
+	"math"
+	"testing"
+)
+
+//rootsMatch reports whether every root found is one of want and every root in want was found, to within tolerance.
+//A repeated root may be reported once or several times.
+func rootsMatch(got []float64, want []float64, tolerance float64) bool {
+	near := func(x float64, set []float64) bool {
+		for _, y := range set {
+			if math.Abs(x-y) <= tolerance {
+				return true
+			}
+		}
+		return false
+	}
+	for _, x := range got {
+		if !near(x, want) {
+			return false
+		}
+	}
+	for _, x := range want {
+		if !near(x, got) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSolveQuadratic(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b, c float64
+		want    []float64
+	}{
+		{"two roots", 1, -3, 2, []float64{1, 2}},
+		{"double root", 1, -4, 4, []float64{2}},
+		{"no roots", 1, 0, 1, nil},
+		{"linear", 0, 2, -4, []float64{2}},
+		{"large and small roots", 1, -1e8, 1, []float64{1e-8, 1e8}},
+	}
+	for _, tt := range tests {
+		got := SolveQuadratic(tt.a, tt.b, tt.c)
+		if !rootsMatch(got, tt.want, 1e-9*math.Max(1, math.Abs(tt.b))) {
+			t.Errorf("%s: SolveQuadratic(%v, %v, %v) = %v, want %v", tt.name, tt.a, tt.b, tt.c, got, tt.want)
+		}
+		for i := 1; i < len(got); i++ {
+			if got[i] < got[i-1] {
+				t.Errorf("%s: roots %v are not in ascending order", tt.name, got)
+			}
+		}
+	}
+}
+
+func TestSolveCubic(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b, c, d float64
+		want       []float64
+	}{
+		//(x-1)(x-2)(x-3)
+		{"three roots", 1, -6, 11, -6, []float64{1, 2, 3}},
+		//(x-1)^2(x+2)
+		{"double root", 1, 0, -3, 2, []float64{-2, 1}},
+		//(x-2)(x^2+1)
+		{"one real root", 1, -2, 1, -2, []float64{2}},
+		//2(x+1)(x-4)(x-0.5)
+		{"scaled", 2, -7, -5, 4, []float64{-1, 0.5, 4}},
+	}
+	for _, tt := range tests {
+		got := SolveCubic(tt.a, tt.b, tt.c, tt.d)
+		if !rootsMatch(got, tt.want, 1e-6) {
+			t.Errorf("%s: SolveCubic(%v, %v, %v, %v) = %v, want %v", tt.name, tt.a, tt.b, tt.c, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestSolveQuartic(t *testing.T) {
+	tests := []struct {
+		name          string
+		a, b, c, d, e float64
+		want          []float64
+	}{
+		//(x-1)(x-2)(x-3)(x-4)
+		{"four roots", 1, -10, 35, -50, 24, []float64{1, 2, 3, 4}},
+		//(x-1)^2(x-3)(x+2)
+		{"double root", 1, -3, -3, 11, -6, []float64{-2, 1, 3}},
+		//(x^2-4)(x^2+1)
+		{"two real roots", 1, 0, -3, 0, -4, []float64{-2, 2}},
+		//x^4+1
+		{"no roots", 1, 0, 0, 0, 1, nil},
+		//(x-2)^2(x+2)^2
+		{"two double roots", 1, 0, -8, 0, 16, []float64{-2, 2}},
+	}
+	for _, tt := range tests {
+		got := SolveQuartic(tt.a, tt.b, tt.c, tt.d, tt.e)
+		if !rootsMatch(got, tt.want, 1e-5) {
+			t.Errorf("%s: SolveQuartic(%v, %v, %v, %v, %v) = %v, want %v", tt.name, tt.a, tt.b, tt.c, tt.d, tt.e, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package scene
+
+import (
+	"fmt"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/shapes"
+)
+
+//Node is an element of the scene graph
+type Node interface {
+	NodeName() string
+}
+
+//Group is a node that places all of its children through its transform, letting an assembly be built once and moved as a unit.
+//The zero Transform is treated as the identity.
+type Group struct {
+	Name      string
+	Transform rays.Matrix4
+	Children  []Node
+}
+
+//ShapeNode is a leaf node holding a shape defined in its parent's coordinate space.
+//If the shape is a mesh, MeshOptions are applied to it when the graph is flattened, such as welding and smoothing an imported mesh.
+type ShapeNode struct {
+	Name        string
+	Shape       shapes.Intersectable
+	MeshOptions shapes.MeshOptions
+}
+
+//Light is a point light, positioned in its parent's coordinate space.  Intensity scales Color, lights loaded from files keep the file's
+//units (candela for glTF point and spot lights, lux for directional ones).
+type Light struct {
+	Name      string
+	Position  rays.Point
+	Color     rays.Point
+	Intensity float32
+}
+
+//Camera is a viewpoint, positioned in its parent's coordinate space.
+//FieldOfView is the vertical angle the camera sees in radians, zero leaves it up to the renderer.
+type Camera struct {
+	Name        string
+	Position    rays.Point
+	LookAt      rays.Point
+	FieldOfView float32
+}
+
+//NodeName returns the name of the group
+func (g *Group) NodeName() string {
+	return g.Name
+}
+
+//NodeName returns the name of the shape node
+func (s *ShapeNode) NodeName() string {
+	return s.Name
+}
+
+//NodeName returns the name of the light
+func (l *Light) NodeName() string {
+	return l.Name
+}
+
+//NodeName returns the name of the camera
+func (c *Camera) NodeName() string {
+	return c.Name
+}
+
+//Scene is a flattened scene graph, with every shape, light and camera in world space
+type Scene struct {
+	Shapes  []shapes.Intersectable
+	Lights  []Light
+	Cameras []Camera
+}
+
+//Find returns the first node named name in the graph below (and including) root, searching depth first, or nil if there is none
+func Find(root Node, name string) Node {
+	if root.NodeName() == name {
+		return root
+	}
+	if g, ok := root.(*Group); ok {
+		for _, child := range g.Children {
+			if found := Find(child, name); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+//Flatten walks the graph below root, combining the transforms of nested groups and returning everything in world space.
+//Shapes under a non-identity transform are wrapped in a shapes.Instance, shapes that end up untransformed are returned as-is.
+func Flatten(root Node) (Scene, error) {
+	var s Scene
+	err := flatten(root, rays.Identity(), &s)
+	return s, err
+}
+
+func flatten(n Node, world rays.Matrix4, s *Scene) error {
+	switch node := n.(type) {
+	case *Group:
+		if node.Transform != (rays.Matrix4{}) {
+			world = world.Multiply(node.Transform)
+		}
+		for _, child := range node.Children {
+			if err := flatten(child, world, s); err != nil {
+				return err
+			}
+		}
+	case *ShapeNode:
+		shape := node.Shape
+		if m, ok := shape.(shapes.Mesh); ok && node.MeshOptions != (shapes.MeshOptions{}) {
+			shape = node.MeshOptions.Process(m)
+		}
+		if world == rays.Identity() {
+			s.Shapes = append(s.Shapes, shape)
+			return nil
+		}
+		instance, err := shapes.NewInstance(shape, world)
+		if err != nil {
+			return fmt.Errorf("shape %q: %v", node.Name, err)
+		}
+		s.Shapes = append(s.Shapes, instance)
+	case *Light:
+		light := *node
+		light.Position = world.TransformPoint(node.Position)
+		s.Lights = append(s.Lights, light)
+	case *Camera:
+		camera := *node
+		camera.Position = world.TransformPoint(node.Position)
+		camera.LookAt = world.TransformPoint(node.LookAt)
+		s.Cameras = append(s.Cameras, camera)
+	default:
+		return fmt.Errorf("node %q: unsupported node type %T", n.NodeName(), n)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package sdf
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Fractal is a distance estimated fractal.  Distance only estimates the distance to the surface and may overestimate it slightly,
+//so fractals should be marched with a step scale below 1.  Trap returns how close the orbit of p came to the fractal's trap, in the range [0,1],
+//for coloring.  Bounds returns the corners of a box the fractal lies within.
+type Fractal interface {
+	Distance(p rays.Point) float32
+	Trap(p rays.Point) float32
+	Bounds() (min rays.Point, max rays.Point)
+}
+
+//mandelbulbBailout is the orbit radius past which a point is known to escape
+const mandelbulbBailout = 2
+
+//Mandelbulb is the 3d Mandelbrot set found by Daniel White and Paul Nylander, iterating z = z^Power + c in spherical coordinates.
+//It is centered on Center and Scale units across its unit radius.  Power 8 gives the classic bulb.
+//See: https://iquilezles.org/articles/mandelbulb/
+type Mandelbulb struct {
+	Center     rays.Point
+	Scale      float32
+	Power      float32
+	Iterations int
+}
+
+//Distance returns the estimated distance from p to the Mandelbulb, 0.5*log(r)*r/dr where dr is the running derivative of the orbit
+func (m Mandelbulb) Distance(p rays.Point) float32 {
+	r, dr, _ := m.orbit(p)
+	if r <= 0 {
+		return 0
+	}
+	return float32(0.5*math.Log(r)*r/dr) * m.Scale
+}
+
+//Trap returns the smallest radius the orbit of p reaches, which is small deep in the folds of the bulb
+func (m Mandelbulb) Trap(p rays.Point) float32 {
+	_, _, trap := m.orbit(p)
+	return clamp(float32(trap), 0, 1)
+}
+
+//Bounds returns the box around the bailout sphere, which every point of the Mandelbulb lies within
+func (m Mandelbulb) Bounds() (min rays.Point, max rays.Point) {
+	extent := mandelbulbBailout * m.Scale
+	return rays.SubtractFloat(m.Center, extent), rays.Add(m.Center, rays.Point{X: extent, Y: extent, Z: extent})
+}
+
+//orbit iterates the Mandelbulb formula at p, returning the final radius, the derivative of the radius and the smallest radius reached
+func (m Mandelbulb) orbit(p rays.Point) (r float64, dr float64, trap float64) {
+	local := rays.Divide(rays.Subtract(p, m.Center), m.Scale)
+	cx, cy, cz := float64(local.X), float64(local.Y), float64(local.Z)
+	x, y, z := cx, cy, cz
+	power := float64(m.Power)
+	dr, trap = 1, math.Inf(1)
+
+	for i := 0; i < m.Iterations; i++ {
+		r = math.Sqrt(x*x + y*y + z*z)
+		if r > mandelbulbBailout {
+			break
+		}
+		if r == 0 {
+			x, y, z = cx, cy, cz
+			continue
+		}
+		dr = math.Pow(r, power-1)*power*dr + 1
+
+		theta := math.Acos(z/r) * power
+		phi := math.Atan2(y, x) * power
+		zr := math.Pow(r, power)
+		x = zr*math.Sin(theta)*math.Cos(phi) + cx
+		y = zr*math.Sin(theta)*math.Sin(phi) + cy
+		z = zr*math.Cos(theta) + cz
+		trap = math.Min(trap, math.Sqrt(x*x+y*y+z*z))
+	}
+	return math.Sqrt(x*x + y*y + z*z), dr, trap
+}
+
+//MengerSponge is a cube around Center, extending HalfSize along each axis, with the middle of every face and the core recursively cut out.
+//See: https://iquilezles.org/articles/menger/
+type MengerSponge struct {
+	Center     rays.Point
+	HalfSize   float32
+	Iterations int
+}
+
+//Distance returns the distance from p to the sponge, subtracting a cross of ever smaller holes from the cube each iteration
+func (m MengerSponge) Distance(p rays.Point) float32 {
+	d, _ := m.carve(p)
+	return d
+}
+
+//Trap returns how many iterations in the hole nearest p was cut, 0 for the faces of the original cube and 1 for the smallest holes
+func (m MengerSponge) Trap(p rays.Point) float32 {
+	_, level := m.carve(p)
+	return float32(level) / float32(maxInt(m.Iterations, 1))
+}
+
+//Bounds returns the corners of the cube the sponge is cut from
+func (m MengerSponge) Bounds() (min rays.Point, max rays.Point) {
+	return rays.SubtractFloat(m.Center, m.HalfSize), rays.Add(m.Center, rays.Point{X: m.HalfSize, Y: m.HalfSize, Z: m.HalfSize})
+}
+
+//carve returns the distance from p to the sponge and the iteration (counting from 1) that cut the hole nearest to it, or 0 for the original cube
+func (m MengerSponge) carve(p rays.Point) (distance float32, level int) {
+	local := rays.Divide(rays.Subtract(p, m.Center), m.HalfSize)
+	d := Box(rays.Point{}, rays.Point{X: 1, Y: 1, Z: 1})(local)
+
+	s := float32(1)
+	for i := 0; i < m.Iterations; i++ {
+		a := rays.Point{X: mod(local.X*s, 2) - 1, Y: mod(local.Y*s, 2) - 1, Z: mod(local.Z*s, 2) - 1}
+		s *= 3
+		r := abs(rays.SubtractFloat(rays.Multiply(abs(a), -3), -1))
+		da, db, dc := maxFloat(r.X, r.Y), maxFloat(r.Y, r.Z), maxFloat(r.Z, r.X)
+		if c := (minFloat(da, minFloat(db, dc)) - 1) / s; c > d {
+			d, level = c, i+1
+		}
+	}
+	return d * m.HalfSize, level
+}
+
+//SierpinskiTetrahedron is a tetrahedron around Center, reaching Size along each axis to its corners, built from four half size copies of itself.
+//It is folded into the corner being approached each iteration and scaled up towards it.
+//See: http://blog.hvidtfeldts.net/index.php/2011/08/distance-estimated-3d-fractals-iii-folding-space/
+type SierpinskiTetrahedron struct {
+	Center     rays.Point
+	Size       float32
+	Iterations int
+}
+
+//Distance returns the distance from p to the smallest tetrahedron it folds into, scaled back down
+func (s SierpinskiTetrahedron) Distance(p rays.Point) float32 {
+	z, scale, _ := s.fold(p)
+	d := (maxFloat(maxFloat(-z.X-z.Y-z.Z, z.X+z.Y-z.Z), maxFloat(z.X-z.Y+z.Z, -z.X+z.Y+z.Z)) - 1) / float32(math.Sqrt(3))
+	return d / scale * s.Size
+}
+
+//Trap returns the smallest distance the folded point comes to the center of a tetrahedron, relative to that tetrahedron's size
+func (s SierpinskiTetrahedron) Trap(p rays.Point) float32 {
+	_, _, trap := s.fold(p)
+	return clamp(trap/float32(math.Sqrt(3)), 0, 1)
+}
+
+//Bounds returns the corners of the box the tetrahedron's corners touch
+func (s SierpinskiTetrahedron) Bounds() (min rays.Point, max rays.Point) {
+	return rays.SubtractFloat(s.Center, s.Size), rays.Add(s.Center, rays.Point{X: s.Size, Y: s.Size, Z: s.Size})
+}
+
+//fold moves p into the tetrahedron's local space and folds it into one corner each iteration, doubling it away from that corner.
+//Returns the folded point, the total scale applied and the smallest distance the point came to the origin.
+func (s SierpinskiTetrahedron) fold(p rays.Point) (z rays.Point, scale float32, trap float32) {
+	z = rays.Divide(rays.Subtract(p, s.Center), s.Size)
+	scale, trap = 1, length(z)
+	for i := 0; i < s.Iterations; i++ {
+		if z.X+z.Y < 0 {
+			z.X, z.Y = -z.Y, -z.X
+		}
+		if z.X+z.Z < 0 {
+			z.X, z.Z = -z.Z, -z.X
+		}
+		if z.Y+z.Z < 0 {
+			z.Y, z.Z = -z.Z, -z.Y
+		}
+		z = rays.SubtractFloat(rays.Multiply(z, 2), 1)
+		scale *= 2
+		trap = minFloat(trap, length(z))
+	}
+	return z, scale, trap
+}
+
+//mod returns v modulo m, always in the range [0,m) even for negative v
+func mod(v float32, m float32) float32 {
+	return v - m*float32(math.Floor(float64(v/m)))
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,94 @@
+package sdf
+
+import (
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Union returns the distance to the nearest of ds
+func Union(ds ...Distance) Distance {
+	return func(p rays.Point) float32 {
+		res := ds[0](p)
+		for _, d := range ds[1:] {
+			res = minFloat(res, d(p))
+		}
+		return res
+	}
+}
+
+//Intersect returns the distance to the solid where a and b overlap
+func Intersect(a Distance, b Distance) Distance {
+	return func(p rays.Point) float32 {
+		return maxFloat(a(p), b(p))
+	}
+}
+
+//Subtract returns the distance to a with b cut out of it
+func Subtract(a Distance, b Distance) Distance {
+	return func(p rays.Point) float32 {
+		return maxFloat(a(p), -b(p))
+	}
+}
+
+//SmoothUnion blends a and b together with a fillet about k wide where they meet, using a polynomial smooth minimum
+func SmoothUnion(a Distance, b Distance, k float32) Distance {
+	return func(p rays.Point) float32 {
+		da, db := a(p), b(p)
+		h := clamp(0.5+0.5*(db-da)/k, 0, 1)
+		return lerp(db, da, h) - k*h*(1-h)
+	}
+}
+
+//SmoothSubtract cuts b out of a, rounding the edges of the cut about k wide
+func SmoothSubtract(a Distance, b Distance, k float32) Distance {
+	return func(p rays.Point) float32 {
+		da, db := a(p), b(p)
+		h := clamp(0.5-0.5*(da+db)/k, 0, 1)
+		return lerp(da, -db, h) + k*h*(1-h)
+	}
+}
+
+//SmoothIntersect returns the solid where a and b overlap, rounding the edges where their surfaces meet about k wide
+func SmoothIntersect(a Distance, b Distance, k float32) Distance {
+	return func(p rays.Point) float32 {
+		da, db := a(p), b(p)
+		h := clamp(0.5-0.5*(db-da)/k, 0, 1)
+		return lerp(db, da, h) + k*h*(1-h)
+	}
+}
+
+//Round grows the surface of d outwards by radius, rounding its edges
+func Round(d Distance, radius float32) Distance {
+	return func(p rays.Point) float32 {
+		return d(p) - radius
+	}
+}
+
+//Translate moves d by offset
+func Translate(d Distance, offset rays.Point) Distance {
+	return func(p rays.Point) float32 {
+		return d(rays.Subtract(p, offset))
+	}
+}
+
+//Scale uniformly scales d by s around the origin
+func Scale(d Distance, s float32) Distance {
+	return func(p rays.Point) float32 {
+		return d(rays.Divide(p, s)) * s
+	}
+}
+
+//Transform places d in the world through m.  m must only rotate and translate, since any scaling would change the distances d returns.
+//Use Scale for uniform scaling.
+func Transform(d Distance, m rays.Matrix4) (Distance, error) {
+	inverse, err := m.Inverse()
+	if err != nil {
+		return nil, err
+	}
+	return func(p rays.Point) float32 {
+		return d(inverse.TransformPoint(p))
+	}, nil
+}
+
+func lerp(a float32, b float32, f float32) float32 {
+	return a + (b-a)*f
+}
@@ -0,0 +1,99 @@
+package sdf
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Distance returns the signed distance from p to a surface, positive outside it and negative inside it.  It may underestimate the true
+//distance but must never overestimate it, or ray marching will step through the surface.
+//Most of the primitives and operators in this package follow: https://iquilezles.org/articles/distfunctions/
+type Distance func(p rays.Point) float32
+
+//Sphere returns the distance to a sphere of radius around center
+func Sphere(center rays.Point, radius float32) Distance {
+	return func(p rays.Point) float32 {
+		return length(rays.Subtract(p, center)) - radius
+	}
+}
+
+//Box returns the distance to an axis aligned box around center, extending halfSize along each axis
+func Box(center rays.Point, halfSize rays.Point) Distance {
+	return RoundBox(center, halfSize, 0)
+}
+
+//RoundBox returns the distance to an axis aligned box around center with its edges rounded by radius.  The box still fits within halfSize.
+func RoundBox(center rays.Point, halfSize rays.Point, radius float32) Distance {
+	inner := rays.SubtractFloat(halfSize, radius)
+	return func(p rays.Point) float32 {
+		q := rays.Subtract(abs(rays.Subtract(p, center)), inner)
+		outside := length(rays.Point{X: maxFloat(q.X, 0), Y: maxFloat(q.Y, 0), Z: maxFloat(q.Z, 0)})
+		inside := minFloat(maxFloat(q.X, maxFloat(q.Y, q.Z)), 0)
+		return outside + inside - radius
+	}
+}
+
+//Torus returns the distance to a ring around center lying in the XZ plane, with the tube of minorRadius centered majorRadius from center
+func Torus(center rays.Point, majorRadius float32, minorRadius float32) Distance {
+	return func(p rays.Point) float32 {
+		rel := rays.Subtract(p, center)
+		ring := length(rays.Point{X: rel.X, Z: rel.Z}) - majorRadius
+		return length(rays.Point{X: ring, Y: rel.Y}) - minorRadius
+	}
+}
+
+//Cylinder returns the distance to a capped cylinder around center along the Y axis, extending halfHeight above and below it
+func Cylinder(center rays.Point, radius float32, halfHeight float32) Distance {
+	return func(p rays.Point) float32 {
+		rel := rays.Subtract(p, center)
+		dx := length(rays.Point{X: rel.X, Z: rel.Z}) - radius
+		dy := float32(math.Abs(float64(rel.Y))) - halfHeight
+		return minFloat(maxFloat(dx, dy), 0) + length(rays.Point{X: maxFloat(dx, 0), Y: maxFloat(dy, 0)})
+	}
+}
+
+//Capsule returns the distance to a line segment from a to b, thickened by radius
+func Capsule(a rays.Point, b rays.Point, radius float32) Distance {
+	ba := rays.Subtract(b, a)
+	baba := rays.DotProduct(ba, ba)
+	return func(p rays.Point) float32 {
+		pa := rays.Subtract(p, a)
+		h := clamp(rays.DotProduct(pa, ba)/baba, 0, 1)
+		return length(rays.Subtract(pa, rays.Multiply(ba, h))) - radius
+	}
+}
+
+//Plane returns the distance to the infinite plane through point, with everything on the side normal points to outside
+func Plane(point rays.Point, normal rays.Point) Distance {
+	n := rays.Divide(normal, length(normal))
+	return func(p rays.Point) float32 {
+		return rays.DotProduct(rays.Subtract(p, point), n)
+	}
+}
+
+func length(p rays.Point) float32 {
+	return float32(math.Sqrt(float64(rays.DotProduct(p, p))))
+}
+
+func abs(p rays.Point) rays.Point {
+	return rays.Point{X: float32(math.Abs(float64(p.X))), Y: float32(math.Abs(float64(p.Y))), Z: float32(math.Abs(float64(p.Z)))}
+}
+
+func clamp(v float32, min float32, max float32) float32 {
+	return minFloat(maxFloat(v, min), max)
+}
+
+func minFloat(a float32, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a float32, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
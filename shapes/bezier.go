@@ -0,0 +1,116 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//maxBezierSteps caps how finely one Bezier patch or curve segment is split, however small the tolerance
+const maxBezierSteps = 64
+
+//BezierPatch is a bicubic Bezier patch given by a 4x4 grid of control points, stored row by row.  u runs along each row and v down the rows.
+//The patch passes through its four corner control points, the others pull the surface towards them.
+type BezierPatch [16]rays.Point
+
+//Evaluate returns the point on the patch at u, v (each in [0,1]) and the directions it moves in as u and v increase
+func (b BezierPatch) Evaluate(u float32, v float32) (p rays.Point, dpdu rays.Point, dpdv rays.Point) {
+	//evaluate each row at u, then the resulting column at v
+	var column, columnDu [4]rays.Point
+	for row := 0; row < 4; row++ {
+		column[row], columnDu[row] = bezier(b[row*4], b[row*4+1], b[row*4+2], b[row*4+3], u)
+	}
+	p, dpdv = bezier(column[0], column[1], column[2], column[3], v)
+	dpdu, _ = bezier(columnDu[0], columnDu[1], columnDu[2], columnDu[3], v)
+	return p, dpdu, dpdv
+}
+
+//NewBezierMesh tessellates Bezier patches, such as the Utah teapot, into a triangle mesh that can be traced, instanced and textured.
+//Each patch is split adaptively, finer where its control points bend more, so the mesh stays within roughly tolerance of the true surface.
+//Vertex normals are taken from the exact patch derivatives so shading is smooth, and each patch is given UVs covering [0,1].
+//Patches are wound so their normals follow dpdv x dpdu, which points outward for the usual patch data sets.
+func NewBezierMesh(patches []BezierPatch, tolerance float32, color rays.Point) Mesh {
+	var vertices, normals []rays.Point
+	var uvs [][2]float32
+	var faces [][3]int
+	for _, b := range patches {
+		uSteps, vSteps := b.steps(tolerance)
+		start := len(vertices)
+		for j := 0; j <= vSteps; j++ {
+			for i := 0; i <= uSteps; i++ {
+				u, v := float32(i)/float32(uSteps), float32(j)/float32(vSteps)
+				p, _, _ := b.Evaluate(u, v)
+				vertices = append(vertices, p)
+				normals = append(normals, b.inwardNormal(u, v))
+				uvs = append(uvs, [2]float32{u, v})
+			}
+		}
+		row := uSteps + 1
+		for j := 0; j < vSteps; j++ {
+			for i := 0; i < uSteps; i++ {
+				a := start + j*row + i
+				faces = append(faces, [3]int{a, a + row, a + row + 1}, [3]int{a, a + row + 1, a + 1})
+			}
+		}
+	}
+
+	m := NewMesh(vertices, faces, color)
+	m.Normals, m.UVs = normals, uvs
+	return m
+}
+
+//steps returns how many pieces to split the patch into along u and v so the flat pieces stay within tolerance of the surface.
+//A cubic split into n even pieces strays at most 3/4 * M / n^2 from its chords, where M is the largest second difference of its control points.
+func (b BezierPatch) steps(tolerance float32) (uSteps int, vSteps int) {
+	var uBend, vBend float32
+	for k := 0; k < 4; k++ {
+		uBend = maxFloat(uBend, secondDifference(b[k*4], b[k*4+1], b[k*4+2], b[k*4+3]))
+		vBend = maxFloat(vBend, secondDifference(b[k], b[k+4], b[k+8], b[k+12]))
+	}
+	return bezierSteps(uBend, tolerance), bezierSteps(vBend, tolerance)
+}
+
+//inwardNormal returns the patch normal at u, v pointing to the back of the patch.  Where a derivative vanishes, such as at a pole where a
+//row of control points meets, the normal is taken from a little way into the patch instead.
+func (b BezierPatch) inwardNormal(u float32, v float32) rays.Point {
+	const nudge = 1e-3
+	for i := 0; i < 4; i++ {
+		_, dpdu, dpdv := b.Evaluate(u, v)
+		if n := cross(dpdu, dpdv); rays.Magnitude(n) > 1e-12 {
+			return normalizeVector(n)
+		}
+		u += (0.5 - u) * nudge * float32(i+1)
+		v += (0.5 - v) * nudge * float32(i+1)
+	}
+	return rays.Point{}
+}
+
+//bezier returns the point at t on the cubic Bezier curve with control points p0 to p3, and its derivative there
+func bezier(p0 rays.Point, p1 rays.Point, p2 rays.Point, p3 rays.Point, t float32) (p rays.Point, dp rays.Point) {
+	s := 1 - t
+	p = rays.Add(rays.Add(rays.Multiply(p0, s*s*s), rays.Multiply(p1, 3*s*s*t)), rays.Add(rays.Multiply(p2, 3*s*t*t), rays.Multiply(p3, t*t*t)))
+	dp = rays.Add(rays.Add(rays.Multiply(rays.Subtract(p1, p0), 3*s*s), rays.Multiply(rays.Subtract(p2, p1), 6*s*t)), rays.Multiply(rays.Subtract(p3, p2), 3*t*t))
+	return p, dp
+}
+
+//secondDifference returns the largest second difference of four control points, which bounds how far a cubic bends away from a straight line
+func secondDifference(p0 rays.Point, p1 rays.Point, p2 rays.Point, p3 rays.Point) float32 {
+	d1 := rays.Add(rays.Subtract(p0, rays.Multiply(p1, 2)), p2)
+	d2 := rays.Add(rays.Subtract(p1, rays.Multiply(p2, 2)), p3)
+	return maxFloat(rays.Magnitude(d1), rays.Magnitude(d2))
+}
+
+//bezierSteps returns the number of even pieces a cubic with largest second difference bend must be split into to stay within tolerance
+func bezierSteps(bend float32, tolerance float32) int {
+	if tolerance <= 0 {
+		return maxBezierSteps
+	}
+	n := int(math.Ceil(math.Sqrt(float64(0.75 * bend / tolerance))))
+	if n < 1 {
+		return 1
+	}
+	if n > maxBezierSteps {
+		return maxBezierSteps
+	}
+	return n
+}
@@ -0,0 +1,136 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//BoundingBox is an axis aligned box enclosing a shape
+type BoundingBox struct {
+	Min rays.Point
+	Max rays.Point
+}
+
+//Bounded is implemented by shapes that can report the box enclosing them.  Unbounded shapes, like an infinite plane, return an infinite box.
+type Bounded interface {
+	Bounds() BoundingBox
+}
+
+//infiniteBounds returns a box enclosing all of space
+func infiniteBounds() BoundingBox {
+	inf := float32(math.Inf(1))
+	return BoundingBox{Min: rays.Point{X: -inf, Y: -inf, Z: -inf}, Max: rays.Point{X: inf, Y: inf, Z: inf}}
+}
+
+//emptyBounds returns a box enclosing nothing, which any union replaces
+func emptyBounds() BoundingBox {
+	inf := float32(math.Inf(1))
+	return BoundingBox{Min: rays.Point{X: inf, Y: inf, Z: inf}, Max: rays.Point{X: -inf, Y: -inf, Z: -inf}}
+}
+
+//BoundsOf returns the bounds of shape i, or an infinite box if it does not report any
+func BoundsOf(i Intersectable) BoundingBox {
+	if b, ok := i.(Bounded); ok {
+		return b.Bounds()
+	}
+	return infiniteBounds()
+}
+
+//IsInfinite returns true if the box is unbounded along any axis
+func (b BoundingBox) IsInfinite() bool {
+	return math.IsInf(float64(b.Min.X), 0) || math.IsInf(float64(b.Min.Y), 0) || math.IsInf(float64(b.Min.Z), 0) ||
+		math.IsInf(float64(b.Max.X), 0) || math.IsInf(float64(b.Max.Y), 0) || math.IsInf(float64(b.Max.Z), 0)
+}
+
+//Union returns the smallest box enclosing both b and o
+func (b BoundingBox) Union(o BoundingBox) BoundingBox {
+	return BoundingBox{
+		Min: rays.Point{X: minFloat(b.Min.X, o.Min.X), Y: minFloat(b.Min.Y, o.Min.Y), Z: minFloat(b.Min.Z, o.Min.Z)},
+		Max: rays.Point{X: maxFloat(b.Max.X, o.Max.X), Y: maxFloat(b.Max.Y, o.Max.Y), Z: maxFloat(b.Max.Z, o.Max.Z)}}
+}
+
+//Extend returns the smallest box enclosing both b and point p
+func (b BoundingBox) Extend(p rays.Point) BoundingBox {
+	return b.Union(BoundingBox{Min: p, Max: p})
+}
+
+//Pad returns b grown by d on every side
+func (b BoundingBox) Pad(d float32) BoundingBox {
+	return BoundingBox{Min: rays.SubtractFloat(b.Min, d), Max: rays.Add(b.Max, rays.Point{X: d, Y: d, Z: d})}
+}
+
+//Center returns the point in the middle of the box
+func (b BoundingBox) Center() rays.Point {
+	return rays.Multiply(rays.Add(b.Min, b.Max), 0.5)
+}
+
+//Contains returns true if point p is inside or on the box
+func (b BoundingBox) Contains(p rays.Point) bool {
+	return p.X >= b.Min.X && p.X <= b.Max.X && p.Y >= b.Min.Y && p.Y <= b.Max.Y && p.Z >= b.Min.Z && p.Z <= b.Max.Z
+}
+
+//Transform returns the box enclosing b after it is moved by m
+func (b BoundingBox) Transform(m rays.Matrix4) BoundingBox {
+	if b.IsInfinite() {
+		return b
+	}
+	res := emptyBounds()
+	for i := 0; i < 8; i++ {
+		corner := b.Min
+		if i&1 != 0 {
+			corner.X = b.Max.X
+		}
+		if i&2 != 0 {
+			corner.Y = b.Max.Y
+		}
+		if i&4 != 0 {
+			corner.Z = b.Max.Z
+		}
+		res = res.Extend(m.TransformPoint(corner))
+	}
+	return res
+}
+
+//Intersect performs the slab test described here: https://www.scratchapixel.com/lessons/3d-basic-rendering/minimal-ray-tracer-rendering-simple-shapes/ray-box-intersection
+//Returns the ray distances where r enters and leaves the box, tNear is negative if the ray starts inside.
+func (b BoundingBox) Intersect(r rays.Ray) (tNear float32, tFar float32, hit bool) {
+	tNear, tFar = float32(math.Inf(-1)), float32(math.Inf(1))
+	origin := [3]float32{r.Origin.X, r.Origin.Y, r.Origin.Z}
+	dir := [3]float32{r.Direction.X, r.Direction.Y, r.Direction.Z}
+	min := [3]float32{b.Min.X, b.Min.Y, b.Min.Z}
+	max := [3]float32{b.Max.X, b.Max.Y, b.Max.Z}
+
+	for axis := 0; axis < 3; axis++ {
+		if dir[axis] == 0 {
+			if origin[axis] < min[axis] || origin[axis] > max[axis] {
+				return 0, 0, false
+			}
+			continue
+		}
+		inv := 1 / dir[axis]
+		t0, t1 := (min[axis]-origin[axis])*inv, (max[axis]-origin[axis])*inv
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		tNear, tFar = maxFloat(tNear, t0), minFloat(tFar, t1)
+		if tNear > tFar {
+			return 0, 0, false
+		}
+	}
+	return tNear, tFar, tFar >= 0
+}
+
+func minFloat(a float32, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a float32, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
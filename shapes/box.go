@@ -0,0 +1,118 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Box represents an axis aligned box between two opposite corners.  Use an Instance to rotate it.
+type Box struct {
+	Min   rays.Point
+	Max   rays.Point
+	Color rays.Point
+	Material
+}
+
+//Equals returns true if the 2 Intersectables are equivalent
+func (b Box) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Box:
+		compare := i.(Box)
+		return b.Min.Equals(compare.Min) && b.Max.Equals(compare.Max)
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect performs the slab test against the box
+func (b Box) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	tNear, tFar, hit := b.Bounds().Intersect(r)
+	if !hit {
+		return false, intersectPoint0, intersectPoint1
+	}
+	return nearestHits(r, tNear, tFar)
+}
+
+//Intervals returns the span ray r spends inside the box
+func (b Box) Intervals(r rays.Ray) []Interval {
+	tNear, tFar, hit := b.Bounds().Intersect(r)
+	if !hit || tNear >= tFar {
+		return nil
+	}
+	return []Interval{{Enter: tNear, Exit: tFar}}
+}
+
+//ColorAtPoint returns the color at a given point.
+func (b Box) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return b.surfaceColor(b.Color, p, b.Min, b)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (b Box) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return b.filteredColor(b.Color, p, b.Min, b, b.GeometricNormalAtPoint(p).Direction, r)
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
+func (b Box) NormalAtPoint(p rays.Point) rays.Ray {
+	return b.shadingNormal(b.GeometricNormalAtPoint(p), b.Min, b)
+}
+
+//GeometricNormalAtPoint returns the inward normal of the face nearest to point p
+func (b Box) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	axis, side := b.faceAtPoint(p)
+	var n [3]float32
+	n[axis] = -side
+	return rays.Ray{Origin: p, Direction: rays.Point{X: n[0], Y: n[1], Z: n[2]}}
+}
+
+//UVAtPoint maps each face of the box to the full [0,1] square, using the two axes the face spans
+func (b Box) UVAtPoint(p rays.Point) (u float32, v float32) {
+	axis, _ := b.faceAtPoint(p)
+	rel := rays.Subtract(p, b.Min)
+	size := rays.Subtract(b.Max, b.Min)
+	switch axis {
+	case 0:
+		return rel.Z / size.Z, rel.Y / size.Y
+	case 1:
+		return rel.X / size.X, rel.Z / size.Z
+	default:
+		return rel.X / size.X, rel.Y / size.Y
+	}
+}
+
+//Bounds returns the box itself
+func (b Box) Bounds() BoundingBox {
+	return BoundingBox{Min: b.Min, Max: b.Max}
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (b Box) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	axis, _ := b.faceAtPoint(p)
+	switch axis {
+	case 0:
+		return rays.Point{Z: 1}, rays.Point{Y: 1}
+	case 1:
+		return rays.Point{X: 1}, rays.Point{Z: 1}
+	default:
+		return rays.Point{X: 1}, rays.Point{Y: 1}
+	}
+}
+
+//faceAtPoint returns the axis (0=X, 1=Y, 2=Z) of the face nearest to p, and side -1 for the Min face or 1 for the Max face
+func (b Box) faceAtPoint(p rays.Point) (axis int, side float32) {
+	pt := [3]float32{p.X, p.Y, p.Z}
+	min := [3]float32{b.Min.X, b.Min.Y, b.Min.Z}
+	max := [3]float32{b.Max.X, b.Max.Y, b.Max.Z}
+
+	best := float32(math.MaxFloat32)
+	for i := 0; i < 3; i++ {
+		if d := float32(math.Abs(float64(pt[i] - min[i]))); d < best {
+			best, axis, side = d, i, -1
+		}
+		if d := float32(math.Abs(float64(pt[i] - max[i]))); d < best {
+			best, axis, side = d, i, 1
+		}
+	}
+	return axis, side
+}
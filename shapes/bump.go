@@ -0,0 +1,90 @@
+package shapes
+
+import (
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/textures"
+)
+
+//bumpDelta is the distance, in world units, between the height samples used to find a bump map's slope
+const bumpDelta = 0.1
+
+//shadowBias is how far shadow rays start off the surface, along the geometric normal, so they do not hit the surface they leave
+const shadowBias = 0.01
+
+//GeometricNormaler is implemented by shapes whose shading normal (NormalAtPoint) can differ from the true surface normal, such as bump mapped shapes
+type GeometricNormaler interface {
+	GeometricNormalAtPoint(p rays.Point) rays.Ray
+}
+
+//GeometricNormal returns the true, unperturbed surface normal of shape i at point p
+func GeometricNormal(i Intersectable, p rays.Point) rays.Ray {
+	if gn, ok := i.(GeometricNormaler); ok {
+		return gn.GeometricNormalAtPoint(p)
+	}
+	return i.NormalAtPoint(p)
+}
+
+//tangentFramer is implemented by shapes that can report how their surface moves as u and v increase, used to orient normal and bump maps
+type tangentFramer interface {
+	UVMapper
+	tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point)
+}
+
+//shadingNormal returns the geometric normal perturbed by the material's normal map or bump map, if it has one.
+//Like all normals in this package the result points into the surface.
+func (m Material) shadingNormal(geometric rays.Ray, origin rays.Point, shape tangentFramer) rays.Ray {
+	if m.NormalMap == nil && m.BumpMap == nil {
+		return geometric
+	}
+
+	p := geometric.Origin
+	outward := rays.Multiply(geometric.Direction, -1)
+	dpdu, dpdv := shape.tangentsAtPoint(p)
+	tangent, bitangent, ok := orthonormalFrame(outward, dpdu, dpdv)
+	if !ok {
+		return geometric
+	}
+
+	if m.NormalMap != nil {
+		c := m.NormalMap.ColorAt(m.sample(p, origin, shape))
+		outward = rays.Add(rays.Add(rays.Multiply(tangent, 2*c.X-1), rays.Multiply(bitangent, 2*c.Y-1)), rays.Multiply(outward, 2*c.Z-1))
+	}
+	if m.BumpMap != nil {
+		height := m.height(p, origin, shape)
+		dhdu := (m.height(rays.Add(p, rays.Multiply(tangent, bumpDelta)), origin, shape) - height) / bumpDelta
+		dhdv := (m.height(rays.Add(p, rays.Multiply(bitangent, bumpDelta)), origin, shape) - height) / bumpDelta
+		slope := rays.Add(rays.Multiply(tangent, dhdu), rays.Multiply(bitangent, dhdv))
+		outward = rays.Subtract(outward, rays.Multiply(slope, m.BumpScale))
+	}
+	return rays.Ray{Origin: p, Direction: rays.Multiply(normalizeVector(outward), -1)}
+}
+
+//height returns the bump map height at p
+func (m Material) height(p rays.Point, origin rays.Point, uv UVMapper) float32 {
+	return textures.Luminance(m.BumpMap.ColorAt(m.sample(p, origin, uv)))
+}
+
+//orthonormalFrame returns unit tangent and bitangent vectors perpendicular to normal n, following dpdu and dpdv as closely as possible.
+//ok is false if the frame is degenerate, such as at the pole of a sphere.
+func orthonormalFrame(n rays.Point, dpdu rays.Point, dpdv rays.Point) (tangent rays.Point, bitangent rays.Point, ok bool) {
+	tangent = rays.Subtract(dpdu, rays.Multiply(n, rays.DotProduct(n, dpdu)))
+	if rays.Magnitude(tangent) < 1e-6 {
+		return tangent, bitangent, false
+	}
+	tangent = normalizeVector(tangent)
+
+	bitangent = cross(n, tangent)
+	if rays.DotProduct(bitangent, dpdv) < 0 {
+		bitangent = rays.Multiply(bitangent, -1)
+	}
+	return tangent, bitangent, true
+}
+
+//shadowRayOrigin nudges p off the surface of shape i along its geometric normal, on the side facing target
+func shadowRayOrigin(i Intersectable, p rays.Point, target rays.Point) rays.Point {
+	n := GeometricNormal(i, p).Direction
+	if rays.DotProduct(n, rays.Subtract(target, p)) < 0 {
+		n = rays.Multiply(n, -1)
+	}
+	return rays.Add(p, rays.Multiply(n, shadowBias))
+}
@@ -0,0 +1,112 @@
+package shapes
+
+import (
+	"sort"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//bvhLeafSize is the most items a bvh leaf holds before it is split
+const bvhLeafSize = 4
+
+//bvh is a bounding volume hierarchy over a set of items known only by their bounding boxes, so a ray or point only has to visit the items near it.
+//See: http://www.pbr-book.org/3ed-2018/Primitives_and_Intersection_Acceleration/Bounding_Volume_Hierarchies.html
+type bvh struct {
+	nodes []bvhNode
+	items []int //item indices, ordered so each leaf's items are contiguous
+}
+
+//bvhNode is a node of a bvh.  Leaves hold count items starting at start, interior nodes have count zero and their children at left and right.
+type bvhNode struct {
+	bounds BoundingBox
+	left   int
+	right  int
+	start  int
+	count  int
+}
+
+//newBVH builds a hierarchy over items with the given bounding boxes, splitting each node at the median item along its longest axis
+func newBVH(boxes []BoundingBox) bvh {
+	b := bvh{items: make([]int, len(boxes))}
+	for i := range b.items {
+		b.items[i] = i
+	}
+	if len(boxes) > 0 {
+		b.build(boxes, 0, len(boxes))
+	}
+	return b
+}
+
+//build adds the node for items[start:end] and its children, returning its index
+func (b *bvh) build(boxes []BoundingBox, start int, end int) int {
+	bounds, centers := emptyBounds(), emptyBounds()
+	for _, item := range b.items[start:end] {
+		bounds = bounds.Union(boxes[item])
+		centers = centers.Extend(boxes[item].Center())
+	}
+	index := len(b.nodes)
+	b.nodes = append(b.nodes, bvhNode{bounds: bounds, start: start, count: end - start})
+	if end-start <= bvhLeafSize {
+		return index
+	}
+
+	extent := rays.Subtract(centers.Max, centers.Min)
+	axis := func(p rays.Point) float32 { return p.X }
+	if extent.Y > extent.X && extent.Y >= extent.Z {
+		axis = func(p rays.Point) float32 { return p.Y }
+	} else if extent.Z > extent.X && extent.Z > extent.Y {
+		axis = func(p rays.Point) float32 { return p.Z }
+	}
+	items := b.items[start:end]
+	sort.Slice(items, func(i, j int) bool { return axis(boxes[items[i]].Center()) < axis(boxes[items[j]].Center()) })
+
+	mid := (start + end) / 2
+	left := b.build(boxes, start, mid)
+	right := b.build(boxes, mid, end)
+	b.nodes[index].left, b.nodes[index].right, b.nodes[index].count = left, right, 0
+	return index
+}
+
+//rayItems calls visit with every item whose leaf box ray r passes through in front of its origin
+func (b bvh) rayItems(r rays.Ray, visit func(item int)) {
+	if len(b.nodes) == 0 {
+		return
+	}
+	stack := []int{0}
+	for len(stack) > 0 {
+		n := b.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if _, _, hit := n.bounds.Intersect(r); !hit {
+			continue
+		}
+		if n.count == 0 {
+			stack = append(stack, n.left, n.right)
+			continue
+		}
+		for _, item := range b.items[n.start : n.start+n.count] {
+			visit(item)
+		}
+	}
+}
+
+//pointItems calls visit with every item whose leaf box contains point p
+func (b bvh) pointItems(p rays.Point, visit func(item int)) {
+	if len(b.nodes) == 0 {
+		return
+	}
+	stack := []int{0}
+	for len(stack) > 0 {
+		n := b.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if !n.bounds.Contains(p) {
+			continue
+		}
+		if n.count == 0 {
+			stack = append(stack, n.left, n.right)
+			continue
+		}
+		for _, item := range b.items[n.start : n.start+n.count] {
+			visit(item)
+		}
+	}
+}
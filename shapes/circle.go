@@ -6,16 +6,13 @@ import (
 	"github.com/flabbergasted/RayTracer/rays"
 )
 
-//Circle represents a 3d sphere, with optional striping and reflectivity.
+//Circle represents a 3d sphere, with an optional texture and reflectivity.
 type Circle struct {
 	Center       rays.Point
 	Radius       float32
 	Color        rays.Point
-	XStripeColor rays.Point
-	XStripeWidth int
-	YStripeColor rays.Point
-	YStripeWidth int
 	Reflectivity float32
+	Material
 }
 
 //Equals returns true if the 2 Intersectables are equivalent
@@ -38,7 +35,8 @@ func (c Circle) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint
 		return false, p0, p1
 	}
 
-	f64 := float64(rays.DotProduct(L, L) - (tca * tca))
+	//rays through the center can round this just below zero
+	f64 := math.Max(float64(rays.DotProduct(L, L)-(tca*tca)), 0)
 	d := math.Sqrt(f64)
 
 	if d < 0 || float32(d) > c.Radius {
@@ -54,24 +52,32 @@ func (c Circle) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint
 	return true, p0, p1
 }
 
+//Intervals returns the span ray r spends inside the sphere
+func (c Circle) Intervals(r rays.Ray) []Interval {
+	o := rays.Subtract(r.Origin, c.Center)
+	t0, t1 := solveQuadratic(rays.DotProduct(r.Direction, r.Direction), 2*rays.DotProduct(o, r.Direction), rays.DotProduct(o, o)-c.Radius*c.Radius)
+	if math.IsNaN(float64(t1)) || t0 >= t1 {
+		return nil
+	}
+	return []Interval{{Enter: t0, Exit: t1}}
+}
+
 //ColorAtPoint returns the color at the given point p
 func (c Circle) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
-	var color rays.Point
-	if c.XStripeWidth != 0 && int(p.X)%10 <= c.XStripeWidth {
-		color = c.XStripeColor
-	} else if c.YStripeWidth != 0 && int(p.Y)%10 <= c.YStripeWidth {
-		color = c.YStripeColor
-	} else {
-		color = c.Color
-	}
+	return c.ColorAtPointDifferential(p, rays.RayDifferential{Ray: rays.Ray{Origin: cameraPosition}})
+}
+
+//ColorAtPointDifferential returns the color at the given point p as seen along r, filtering textures over the ray's footprint
+func (c Circle) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	surfaceNormal := c.NormalAtPoint(p)
+	color := c.filteredColor(c.Color, p, c.Center, c, surfaceNormal.Direction, r)
 
 	if c.Reflectivity == 0 {
 		return color
 	}
 
-	intersectionRay := rays.Ray{Direction: rays.Normalize(cameraPosition, p), Origin: cameraPosition}
-	surfaceNormal := c.NormalAtPoint(p)
-	reflectRay := rays.RayFromAngle(surfaceNormal, intersectionRay)
+	r.Ray = rays.Ray{Direction: rays.Normalize(r.Origin, p), Origin: r.Origin}
+	reflectRay := reflectDifferential(c, p, surfaceNormal, r)
 
 	reflectMag := float32(100000)
 	var zeroPoint, reflectedPoint rays.Point
@@ -79,8 +85,8 @@ func (c Circle) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point
 
 	//check shapes list for intersection, if one is found then show that color for this point.
 	for _, e := range ReflectiveObjects {
-		if do, intersectPoint, _ := e.DoesRayIntersect(reflectRay); do && !e.Equals(c) {
-			newMag := rays.MagnitudeRay(reflectRay)
+		if do, intersectPoint, _ := e.DoesRayIntersect(reflectRay.Ray); do && !e.Equals(c) {
+			newMag := rays.MagnitudeRay(reflectRay.Ray)
 			if reflectMag > newMag {
 				reflectMag = newMag
 				reflectedPoint = intersectPoint
@@ -90,13 +96,54 @@ func (c Circle) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point
 	}
 
 	if !reflectedPoint.Equals(zeroPoint) {
-		return reflectedObject.ColorAtPoint(reflectedPoint, cameraPosition)
+		return Fog.Apply(reflectRay.Ray, rays.Magnitude(rays.Subtract(reflectedPoint, p)), ColorAt(reflectedObject, reflectedPoint, reflectRay))
 	}
-	return rays.Point{X: 0.0, Y: 0.0, Z: 0.0}
+	return Fog.Apply(reflectRay.Ray, float32(math.Inf(1)), rays.Point{X: 0.0, Y: 0.0, Z: 0.0})
 }
 
-//NormalAtPoint returns the surface normal for this intersectable shape at point p
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
 func (c Circle) NormalAtPoint(p rays.Point) rays.Ray {
+	return c.shadingNormal(c.GeometricNormalAtPoint(p), c.Center, c)
+}
+
+//GeometricNormalAtPoint returns the true surface normal of the sphere at point p
+func (c Circle) GeometricNormalAtPoint(p rays.Point) rays.Ray {
 	normal := rays.Normalize(p, c.Center)
 	return rays.Ray{Origin: p, Direction: normal}
 }
+
+//UVAtPoint returns spherical coordinates for point p, u runs around the Y axis and v runs from the top of the sphere (lowest Y) to the bottom
+func (c Circle) UVAtPoint(p rays.Point) (u float32, v float32) {
+	d := rays.Normalize(c.Center, p)
+	u = 0.5 + float32(math.Atan2(float64(d.Z), float64(d.X))/(2*math.Pi))
+	v = 0.5 + float32(math.Asin(float64(clampUnit(d.Y)))/math.Pi)
+	return u, v
+}
+
+//Bounds returns the box enclosing the sphere
+func (c Circle) Bounds() BoundingBox {
+	return BoundingBox{Min: rays.SubtractFloat(c.Center, c.Radius), Max: rays.Add(c.Center, rays.Point{X: c.Radius, Y: c.Radius, Z: c.Radius})}
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (c Circle) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	d := rays.Normalize(c.Center, p)
+	rho := float32(math.Sqrt(float64(d.X*d.X + d.Z*d.Z)))
+	if rho == 0 {
+		return dpdu, dpdv
+	}
+	dpdu = rays.Point{X: -d.Z, Y: 0, Z: d.X}
+	dpdv = rays.Point{X: -d.Y * d.X / rho, Y: rho, Z: -d.Y * d.Z / rho}
+	return dpdu, dpdv
+}
+
+//clampUnit keeps v within [-1,1], guarding asin/acos against rounding error
+func clampUnit(v float32) float32 {
+	if v < -1 {
+		return -1
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
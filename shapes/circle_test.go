@@ -0,0 +1,32 @@
+package shapes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+func TestCircleRayThroughCenter(t *testing.T) {
+	c := Circle{Center: rays.Point{X: 250, Y: 500, Z: 150}, Radius: 100}
+	//rounding can put these rays a hair's breadth past the center, which must not turn the hits into NaN
+	origins := []rays.Point{
+		{X: -338, Y: -274, Z: -364},
+		{X: -586.9, Y: -488.7, Z: -27.7},
+		{X: -390.4, Y: -319.2, Z: -293.2},
+	}
+	for _, o := range origins {
+		r := rays.Ray{Origin: o, Direction: rays.Normalize(o, c.Center)}
+		hit, p0, p1 := c.DoesRayIntersect(r)
+		if !hit {
+			t.Errorf("ray from %v through the center missed", o)
+			continue
+		}
+		for _, p := range []rays.Point{p0, p1} {
+			d := rays.Magnitude(rays.Subtract(p, c.Center))
+			if math.IsNaN(float64(d)) || absFloat(d-c.Radius) > 1e-2 {
+				t.Errorf("ray from %v hit at %v, %v from the center, want %v", o, p, d, c.Radius)
+			}
+		}
+	}
+}
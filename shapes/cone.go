@@ -0,0 +1,128 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Cone represents a capped cone with a base disk of Radius centered on Base, narrowing to its apex Height along the Y axis.
+//Use an Instance to orient it along another axis.
+type Cone struct {
+	Base   rays.Point
+	Radius float32
+	Height float32
+	Color  rays.Point
+	Material
+}
+
+//Equals returns true if the 2 Intersectables are equivalent
+func (c Cone) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Cone:
+		compare := i.(Cone)
+		return c.Base.Equals(compare.Base) && c.Radius == compare.Radius && c.Height == compare.Height
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect tests the sloped side as a quadratic, x^2 + z^2 = (k(h-y))^2 where k is the radius to height ratio, and the base as a disk
+func (c Cone) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	return nearestHits(r, c.hitDistances(r)...)
+}
+
+//Intervals returns the span ray r spends inside the cone
+func (c Cone) Intervals(r rays.Ray) []Interval {
+	return pairIntervals(c.hitDistances(r))
+}
+
+//hitDistances returns the ray distances of every crossing of the cone's surface, in no particular order
+func (c Cone) hitDistances(r rays.Ray) []float32 {
+	o := rays.Subtract(r.Origin, c.Base)
+	d := r.Direction
+	k := c.Radius / c.Height
+	k2 := k * k
+	h := c.Height - o.Y
+	res := make([]float32, 0, 3)
+
+	t0, t1 := solveQuadratic(d.X*d.X+d.Z*d.Z-k2*d.Y*d.Y, 2*(o.X*d.X+o.Z*d.Z+k2*h*d.Y), o.X*o.X+o.Z*o.Z-k2*h*h)
+	for _, t := range []float32{t0, t1} {
+		if y := o.Y + t*d.Y; y >= 0 && y <= c.Height {
+			res = append(res, t)
+		}
+	}
+
+	if d.Y != 0 {
+		t := -o.Y / d.Y
+		x, z := o.X+t*d.X, o.Z+t*d.Z
+		if x*x+z*z <= c.Radius*c.Radius {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+//ColorAtPoint returns the color at a given point.
+func (c Cone) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return c.surfaceColor(c.Color, p, c.Base, c)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (c Cone) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return c.filteredColor(c.Color, p, c.Base, c, c.GeometricNormalAtPoint(p).Direction, r)
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
+func (c Cone) NormalAtPoint(p rays.Point) rays.Ray {
+	return c.shadingNormal(c.GeometricNormalAtPoint(p), c.Base, c)
+}
+
+//GeometricNormalAtPoint returns the inward normal of the side or base nearest to point p
+func (c Cone) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	if c.partAtPoint(p) == bottomCap {
+		return rays.Ray{Origin: p, Direction: rays.Point{Y: 1}}
+	}
+	rel := rays.Subtract(p, c.Base)
+	rho := float32(math.Sqrt(float64(rel.X*rel.X + rel.Z*rel.Z)))
+	outward := rays.Point{X: rel.X, Y: rho * c.Radius / c.Height, Z: rel.Z}
+	return rays.Ray{Origin: p, Direction: rays.Multiply(normalizeVector(outward), -1)}
+}
+
+//UVAtPoint wraps u around the side with v running up to the apex, the base is mapped as a square around the disk
+func (c Cone) UVAtPoint(p rays.Point) (u float32, v float32) {
+	rel := rays.Subtract(p, c.Base)
+	if c.partAtPoint(p) == bottomCap {
+		return 0.5 + rel.X/(2*c.Radius), 0.5 + rel.Z/(2*c.Radius)
+	}
+	return 0.5 + float32(math.Atan2(float64(rel.Z), float64(rel.X))/(2*math.Pi)), rel.Y / c.Height
+}
+
+//Bounds returns the box enclosing the cone
+func (c Cone) Bounds() BoundingBox {
+	return BoundingBox{
+		Min: rays.Point{X: c.Base.X - c.Radius, Y: c.Base.Y, Z: c.Base.Z - c.Radius},
+		Max: rays.Point{X: c.Base.X + c.Radius, Y: c.Base.Y + c.Height, Z: c.Base.Z + c.Radius}}
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (c Cone) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	rel := rays.Subtract(p, c.Base)
+	if c.partAtPoint(p) == bottomCap {
+		return rays.Point{X: 1}, rays.Point{Z: 1}
+	}
+	toApex := rays.Subtract(rays.Point{Y: c.Height}, rel)
+	return rays.Point{X: -rel.Z, Z: rel.X}, toApex
+}
+
+func (c Cone) partAtPoint(p rays.Point) capPart {
+	rel := rays.Subtract(p, c.Base)
+	rho := float32(math.Sqrt(float64(rel.X*rel.X + rel.Z*rel.Z)))
+	//distance to the slanted side, measured perpendicular to it
+	slant := float32(math.Sqrt(float64(c.Radius*c.Radius + c.Height*c.Height)))
+	sideDist := float32(math.Abs(float64((rho*c.Height + rel.Y*c.Radius - c.Radius*c.Height) / slant)))
+	if nearestPart(sideDist, rel.Y, math.MaxFloat32) == bottomCap {
+		return bottomCap
+	}
+	return side
+}
@@ -0,0 +1,251 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//csgProbe is how far before a point the probe rays used to find which child's surface it lies on start
+const csgProbe = 1
+
+//Interval is a span of ray distances, from where the ray enters a solid to where it leaves it
+type Interval struct {
+	Enter float32
+	Exit  float32
+}
+
+//IntervalIntersectable is implemented by solid shapes that can report every span a ray spends inside them, which CSG needs to combine them.
+//Intervals cover the whole line the ray lies on, so spans before the ray origin may be included.  They are returned in ascending order and do not overlap.
+type IntervalIntersectable interface {
+	Intervals(r rays.Ray) []Interval
+}
+
+//IntervalsOf returns the spans ray r spends inside shape i.  Shapes that do not report intervals are treated as solid between the two points DoesRayIntersect returns.
+func IntervalsOf(i Intersectable, r rays.Ray) []Interval {
+	if ii, ok := i.(IntervalIntersectable); ok {
+		return ii.Intervals(r)
+	}
+	do, p0, p1 := i.DoesRayIntersect(r)
+	if !do {
+		return nil
+	}
+	dd := rays.DotProduct(r.Direction, r.Direction)
+	t0 := rays.DotProduct(rays.Subtract(p0, r.Origin), r.Direction) / dd
+	t1 := rays.DotProduct(rays.Subtract(p1, r.Origin), r.Direction) / dd
+	if t0 >= t1 {
+		return nil
+	}
+	return []Interval{{Enter: t0, Exit: t1}}
+}
+
+//pairIntervals turns the distances of every crossing of a closed surface into the spans between them.
+//Crossings closer than hitEpsilon are treated as one, such as a ray passing through the rim between a cylinder's side and cap.
+//If an odd number remain the ray only grazed the surface somewhere, and it is treated as a miss.
+func pairIntervals(ts []float32) []Interval {
+	sorted := make([]float32, 0, len(ts))
+	for _, t := range ts {
+		if !math.IsNaN(float64(t)) {
+			sorted = append(sorted, t)
+		}
+	}
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+
+	crossings := sorted[:0]
+	for _, t := range sorted {
+		if n := len(crossings); n > 0 && t-crossings[n-1] < hitEpsilon {
+			continue
+		}
+		crossings = append(crossings, t)
+	}
+	if len(crossings)%2 != 0 {
+		return nil
+	}
+
+	res := make([]Interval, 0, len(crossings)/2)
+	for i := 0; i < len(crossings); i += 2 {
+		res = append(res, Interval{Enter: crossings[i], Exit: crossings[i+1]})
+	}
+	return res
+}
+
+//insideIntervals returns true if distance t lies in one of the intervals
+func insideIntervals(intervals []Interval, t float32) bool {
+	for _, in := range intervals {
+		if t >= in.Enter && t <= in.Exit {
+			return true
+		}
+	}
+	return false
+}
+
+//CSGOperation selects how a CSG node combines its two children
+type CSGOperation int
+
+const (
+	//CSGUnion is solid wherever either child is
+	CSGUnion CSGOperation = iota
+	//CSGIntersection is solid only where both children are
+	CSGIntersection
+	//CSGDifference is solid where Left is and Right is not, so Right takes a bite out of Left
+	CSGDifference
+)
+
+//keep returns true if a point inside (or not) each child is inside the combined solid
+func (op CSGOperation) keep(inLeft bool, inRight bool) bool {
+	switch op {
+	case CSGIntersection:
+		return inLeft && inRight
+	case CSGDifference:
+		return inLeft && !inRight
+	default:
+		return inLeft || inRight
+	}
+}
+
+//CSG combines two solid shapes with a boolean operation.  Children are combined through the spans the ray spends inside each one (see IntervalIntersectable),
+//and CSG nodes can be nested.  The surface at a point takes its normal and material from the child it belongs to, with the normals of a subtracted child flipped.
+//Combine unlit shapes and wrap the CSG node in lighting, so the lighting sees the flipped normals.
+type CSG struct {
+	Operation CSGOperation
+	Left      Intersectable
+	Right     Intersectable
+}
+
+//Equals returns true if the 2 Intersectables are equivalent
+func (c CSG) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case CSG:
+		compare := i.(CSG)
+		return c.Operation == compare.Operation && c.Left.Equals(compare.Left) && c.Right.Equals(compare.Right)
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect combines the children's intervals and returns the nearest and furthest surface crossings in front of the ray
+func (c CSG) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	intervals := c.Intervals(r)
+	ts := make([]float32, 0, 2*len(intervals))
+	for _, in := range intervals {
+		ts = append(ts, in.Enter, in.Exit)
+	}
+	return nearestHits(r, ts...)
+}
+
+//Intervals returns the spans ray r spends inside the combined solid
+func (c CSG) Intervals(r rays.Ray) []Interval {
+	left := IntervalsOf(c.Left, r)
+	if len(left) == 0 && c.Operation != CSGUnion {
+		return nil
+	}
+	right := IntervalsOf(c.Right, r)
+
+	ts := make([]float32, 0, 2*(len(left)+len(right)))
+	for _, in := range left {
+		ts = append(ts, in.Enter, in.Exit)
+	}
+	for _, in := range right {
+		ts = append(ts, in.Enter, in.Exit)
+	}
+	sort.Slice(ts, func(a, b int) bool { return ts[a] < ts[b] })
+
+	//classify the span between each pair of neighbouring crossings by its midpoint, merging kept spans that touch
+	var res []Interval
+	for i := 0; i+1 < len(ts); i++ {
+		if ts[i] == ts[i+1] {
+			continue
+		}
+		mid := (ts[i] + ts[i+1]) / 2
+		if !c.Operation.keep(insideIntervals(left, mid), insideIntervals(right, mid)) {
+			continue
+		}
+		if n := len(res); n > 0 && res[n-1].Exit == ts[i] {
+			res[n-1].Exit = ts[i+1]
+			continue
+		}
+		res = append(res, Interval{Enter: ts[i], Exit: ts[i+1]})
+	}
+	return res
+}
+
+//ColorAtPoint returns the color of the child whose surface point p lies on
+func (c CSG) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	child, _ := c.surfaceAtPoint(p)
+	return child.ColorAtPoint(p, cameraPosition)
+}
+
+//ColorAtPointDifferential returns the color of the child whose surface point p lies on, as seen along r
+func (c CSG) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	child, _ := c.surfaceAtPoint(p)
+	return ColorAt(child, p, r)
+}
+
+//NormalAtPoint returns the shading normal of the child whose surface point p lies on, pointing into the combined solid
+func (c CSG) NormalAtPoint(p rays.Point) rays.Ray {
+	child, flip := c.surfaceAtPoint(p)
+	return flipNormal(child.NormalAtPoint(p), flip)
+}
+
+//GeometricNormalAtPoint returns the true surface normal of the child whose surface point p lies on, pointing into the combined solid
+func (c CSG) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	child, flip := c.surfaceAtPoint(p)
+	return flipNormal(GeometricNormal(child, p), flip)
+}
+
+//UVAtPoint forwards the call to the child whose surface point p lies on, if it provides a surface parameterization
+func (c CSG) UVAtPoint(p rays.Point) (u float32, v float32) {
+	child, _ := c.surfaceAtPoint(p)
+	if uv, ok := child.(UVMapper); ok {
+		return uv.UVAtPoint(p)
+	}
+	return 0, 0
+}
+
+//Bounds returns the box enclosing the combined solid
+func (c CSG) Bounds() BoundingBox {
+	left := BoundsOf(c.Left)
+	switch c.Operation {
+	case CSGIntersection:
+		right := BoundsOf(c.Right)
+		return BoundingBox{
+			Min: rays.Point{X: maxFloat(left.Min.X, right.Min.X), Y: maxFloat(left.Min.Y, right.Min.Y), Z: maxFloat(left.Min.Z, right.Min.Z)},
+			Max: rays.Point{X: minFloat(left.Max.X, right.Max.X), Y: minFloat(left.Max.Y, right.Max.Y), Z: minFloat(left.Max.Z, right.Max.Z)}}
+	case CSGDifference:
+		return left
+	default:
+		return left.Union(BoundsOf(c.Right))
+	}
+}
+
+//surfaceAtPoint returns the child whose surface point p lies on, and whether its normals must be flipped because it was subtracted.
+//Each child is probed with a short ray along its own normal through p, and the one whose surface the probe crosses closest to p wins.
+func (c CSG) surfaceAtPoint(p rays.Point) (child Intersectable, flip bool) {
+	if c.surfaceDistance(c.Right, p) < c.surfaceDistance(c.Left, p) {
+		return c.Right, c.Operation == CSGDifference
+	}
+	return c.Left, false
+}
+
+//surfaceDistance returns how far point p is from the surface of child, measured along the child's normal at p
+func (c CSG) surfaceDistance(child Intersectable, p rays.Point) float32 {
+	n := GeometricNormal(child, p).Direction
+	probe := rays.Ray{Origin: rays.Subtract(p, rays.Multiply(n, csgProbe)), Direction: n}
+	best := float32(math.Inf(1))
+	for _, in := range IntervalsOf(child, probe) {
+		best = minFloat(best, minFloat(absFloat(in.Enter-csgProbe), absFloat(in.Exit-csgProbe)))
+	}
+	return best
+}
+
+func flipNormal(n rays.Ray, flip bool) rays.Ray {
+	if flip {
+		n.Direction = rays.Multiply(n.Direction, -1)
+	}
+	return n
+}
+
+func absFloat(v float32) float32 {
+	return float32(math.Abs(float64(v)))
+}
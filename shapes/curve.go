@@ -0,0 +1,314 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//CurveType selects the cross section of a Curves shape
+type CurveType int
+
+const (
+	//TubeCurve strands are round tubes, for hair and fur
+	TubeCurve CurveType = iota
+	//RibbonCurve strands are flat strips facing the strand's Normals, for grass blades and leaves
+	RibbonCurve
+)
+
+//Strand is one curve of a Curves shape: a cubic Bezier spline through Points, which hold 3n+1 control points for n segments
+//(each segment shares its last point with the next one's first).  Widths holds the width at the start and end of every segment, n+1 values
+//interpolated along the strand.  Ribbon strands also need Normals, n+1 directions the flat side faces, again interpolated along the strand.
+type Strand struct {
+	Points  []rays.Point
+	Widths  []float32
+	Normals []rays.Point
+}
+
+//Curves is a set of thin strands, such as hair, fur or grass, sharing one color and material.  Every strand is flattened into short straight
+//pieces, which are tested through a hierarchy so large numbers of strands stay fast.  u runs across the strand and v along it, each in [0,1].
+//Strand data lives in slices, so copies of a Curves share it.  Create one with NewCurves.
+type Curves struct {
+	Type  CurveType
+	Color rays.Point
+	Material
+	pieces []curvePiece
+	tree   bvh
+	bounds BoundingBox
+}
+
+//curvePiece is a straight piece of a strand, running from A to B with a width and ribbon normal at each end.  V0 and V1 are the strand's
+//v coordinates at its ends.
+type curvePiece struct {
+	A, B                  rays.Point
+	WidthA, WidthB        float32
+	NormalA, NormalB      rays.Point
+	V0, V1                float32
+	tangent, sideA, sideB rays.Point
+}
+
+//NewCurves creates a set of strands of the given type.  Each Bezier segment is split finely enough that the straight pieces stay within a
+//quarter of the strand's width of the true curve.
+func NewCurves(strands []Strand, curveType CurveType, color rays.Point) (Curves, error) {
+	c := Curves{Type: curveType, Color: color, bounds: emptyBounds()}
+	var boxes []BoundingBox
+	for i, s := range strands {
+		if len(s.Points) < 4 || (len(s.Points)-1)%3 != 0 {
+			return Curves{}, fmt.Errorf("strand %d has %d control points, it needs 3n+1 for n cubic segments", i, len(s.Points))
+		}
+		segments := (len(s.Points) - 1) / 3
+		if len(s.Widths) != segments+1 {
+			return Curves{}, fmt.Errorf("strand %d has %d widths, it needs %d", i, len(s.Widths), segments+1)
+		}
+		if curveType == RibbonCurve && len(s.Normals) != segments+1 {
+			return Curves{}, fmt.Errorf("strand %d has %d normals, a ribbon needs %d", i, len(s.Normals), segments+1)
+		}
+
+		for seg := 0; seg < segments; seg++ {
+			p := s.Points[seg*3 : seg*3+4]
+			w0, w1 := s.Widths[seg], s.Widths[seg+1]
+			steps := bezierSteps(secondDifference(p[0], p[1], p[2], p[3]), 0.25*minFloat(w0, w1))
+			prev, _ := bezier(p[0], p[1], p[2], p[3], 0)
+			for k := 1; k <= steps; k++ {
+				t0, t1 := float32(k-1)/float32(steps), float32(k)/float32(steps)
+				next, _ := bezier(p[0], p[1], p[2], p[3], t1)
+				piece := curvePiece{
+					A: prev, B: next,
+					WidthA: w0 + (w1-w0)*t0, WidthB: w0 + (w1-w0)*t1,
+					V0: (float32(seg) + t0) / float32(segments), V1: (float32(seg) + t1) / float32(segments),
+				}
+				if curveType == RibbonCurve {
+					n0, n1 := normalizeVector(s.Normals[seg]), normalizeVector(s.Normals[seg+1])
+					piece.NormalA = normalizeVector(rays.Add(rays.Multiply(n0, 1-t0), rays.Multiply(n1, t0)))
+					piece.NormalB = normalizeVector(rays.Add(rays.Multiply(n0, 1-t1), rays.Multiply(n1, t1)))
+				}
+				piece.prepare()
+				c.pieces = append(c.pieces, piece)
+				box := BoundingBox{Min: piece.A, Max: piece.A}.Extend(piece.B).Pad(maxFloat(piece.WidthA, piece.WidthB)/2 + meshEpsilon*10)
+				boxes = append(boxes, box)
+				c.bounds = c.bounds.Union(box)
+				prev = next
+			}
+		}
+	}
+	if len(c.pieces) == 0 {
+		return Curves{}, errors.New("curves need at least one strand")
+	}
+	c.tree = newBVH(boxes)
+	return c, nil
+}
+
+//prepare precalculates the piece's direction and, for ribbons, the sideways direction of the strip at each end
+func (cp *curvePiece) prepare() {
+	cp.tangent = normalizeVector(rays.Subtract(cp.B, cp.A))
+	cp.sideA = normalizeVector(cross(cp.tangent, cp.NormalA))
+	cp.sideB = normalizeVector(cross(cp.tangent, cp.NormalB))
+}
+
+//Equals returns true if the 2 Intersectables are equivalent.  Curves are equal when they share the same strand data.
+func (c Curves) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Curves:
+		compare := i.(Curves)
+		if len(c.pieces) == 0 || len(compare.pieces) == 0 {
+			return len(c.pieces) == len(compare.pieces)
+		}
+		return &c.pieces[0] == &compare.pieces[0]
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect finds the nearest piece the ray hits.  Strands are thin, so the ray is treated as hitting the surface once and both
+//points returned are the same.
+func (c Curves) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	near := float32(math.MaxFloat32)
+	c.tree.rayItems(r, func(i int) {
+		var t float32
+		var hit bool
+		if c.Type == RibbonCurve {
+			t, hit = c.pieces[i].intersectRibbon(r)
+		} else {
+			t, hit = c.pieces[i].intersectTube(r)
+		}
+		if hit && t < near {
+			near = t
+		}
+	})
+	if near == math.MaxFloat32 {
+		return false, intersectPoint0, intersectPoint1
+	}
+	p := rays.Add(r.Origin, rays.Multiply(r.Direction, near))
+	return true, p, p
+}
+
+//intersectTube intersects r with the piece as a round tube whose radius changes along it.  The tube is treated as a cylinder with the radius
+//at the point of closest approach, which is exact for constant widths and close for the slow taper of a thin strand.
+func (cp curvePiece) intersectTube(r rays.Ray) (float32, bool) {
+	//closest approach between the ray and the piece's axis
+	w := rays.Subtract(r.Origin, cp.A)
+	length := rays.Magnitude(rays.Subtract(cp.B, cp.A))
+	dd, dt := rays.DotProduct(r.Direction, r.Direction), rays.DotProduct(r.Direction, cp.tangent)
+	dw, tw := rays.DotProduct(r.Direction, w), rays.DotProduct(cp.tangent, w)
+	denom := dd - dt*dt
+	if denom < 1e-12 {
+		return 0, false
+	}
+	t := (dt*tw - dw) / denom
+	s := tw + t*dt
+	closing := denom //how fast the squared distance to the axis grows with t, per unit of t squared
+	if s < 0 || s > length {
+		//the ray passes beyond an end, where the piece is capped by a sphere so it joins smoothly with the next piece
+		s = clampFloat(s, 0, length)
+		closing = dd
+	}
+
+	//closest approach between the ray and the axis point at s
+	axis := rays.Add(cp.A, rays.Multiply(cp.tangent, s))
+	t = rays.DotProduct(rays.Subtract(axis, r.Origin), r.Direction) / dd
+	offset := rays.Subtract(rays.Add(r.Origin, rays.Multiply(r.Direction, t)), axis)
+	radius := cp.widthAt(s/maxFloat(length, hitEpsilon)) / 2
+	dist2 := rays.DotProduct(offset, offset)
+	if dist2 > radius*radius {
+		return 0, false
+	}
+
+	//step back from the closest approach to the tube wall
+	t -= float32(math.Sqrt(float64((radius*radius - dist2) / closing)))
+	return t, t > hitEpsilon
+}
+
+//intersectRibbon intersects r with the piece as a flat strip, split into two triangles
+func (cp curvePiece) intersectRibbon(r rays.Ray) (float32, bool) {
+	a0 := rays.Subtract(cp.A, rays.Multiply(cp.sideA, cp.WidthA/2))
+	a1 := rays.Add(cp.A, rays.Multiply(cp.sideA, cp.WidthA/2))
+	b0 := rays.Subtract(cp.B, rays.Multiply(cp.sideB, cp.WidthB/2))
+	b1 := rays.Add(cp.B, rays.Multiply(cp.sideB, cp.WidthB/2))
+	if t, _, _, hit := intersectTriangle(r, a0, a1, b1); hit {
+		return t, true
+	}
+	if t, _, _, hit := intersectTriangle(r, a0, b1, b0); hit {
+		return t, true
+	}
+	return 0, false
+}
+
+//widthAt returns the width a fraction f of the way along the piece
+func (cp curvePiece) widthAt(f float32) float32 {
+	return cp.WidthA + (cp.WidthB-cp.WidthA)*f
+}
+
+//ColorAtPoint returns the color at a given point.
+func (c Curves) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return c.surfaceColor(c.Color, p, c.bounds.Center(), c)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (c Curves) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return c.filteredColor(c.Color, p, c.bounds.Center(), c, c.GeometricNormalAtPoint(p).Direction, r)
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
+func (c Curves) NormalAtPoint(p rays.Point) rays.Ray {
+	return c.shadingNormal(c.GeometricNormalAtPoint(p), c.bounds.Center(), c)
+}
+
+//GeometricNormalAtPoint returns the inward normal at p: towards the axis for a tube, or against the ribbon's facing direction for a ribbon
+func (c Curves) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	i, f := c.pieceAtPoint(p)
+	if i < 0 {
+		return rays.Ray{Origin: p}
+	}
+	cp := c.pieces[i]
+	if c.Type == RibbonCurve {
+		n := rays.Add(rays.Multiply(cp.NormalA, 1-f), rays.Multiply(cp.NormalB, f))
+		return rays.Ray{Origin: p, Direction: normalizeVector(rays.Multiply(n, -1))}
+	}
+	axis := rays.Add(cp.A, rays.Multiply(rays.Subtract(cp.B, cp.A), f))
+	in := rays.Subtract(axis, p)
+	in = rays.Subtract(in, rays.Multiply(cp.tangent, rays.DotProduct(in, cp.tangent)))
+	return rays.Ray{Origin: p, Direction: normalizeVector(in)}
+}
+
+//UVAtPoint returns how far across (u) and along (v) the strand p lies.  Across a tube, u goes once around it.
+func (c Curves) UVAtPoint(p rays.Point) (u float32, v float32) {
+	i, f := c.pieceAtPoint(p)
+	if i < 0 {
+		return 0, 0
+	}
+	cp := c.pieces[i]
+	v = cp.V0 + (cp.V1-cp.V0)*f
+	axis := rays.Add(cp.A, rays.Multiply(rays.Subtract(cp.B, cp.A), f))
+	offset := rays.Subtract(p, axis)
+	if c.Type == RibbonCurve {
+		side := normalizeVector(rays.Add(rays.Multiply(cp.sideA, 1-f), rays.Multiply(cp.sideB, f)))
+		return clampFloat(0.5+rays.DotProduct(offset, side)/cp.widthAt(f), 0, 1), v
+	}
+	//angle around the axis, measured from a direction perpendicular to it
+	x := perpendicular(cp.tangent)
+	y := cross(cp.tangent, x)
+	angle := math.Atan2(float64(rays.DotProduct(offset, y)), float64(rays.DotProduct(offset, x)))
+	return float32(angle/(2*math.Pi) + 0.5), v
+}
+
+//Bounds returns the box enclosing every strand
+func (c Curves) Bounds() BoundingBox {
+	return c.bounds
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (c Curves) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	i, f := c.pieceAtPoint(p)
+	if i < 0 {
+		return dpdu, dpdv
+	}
+	cp := c.pieces[i]
+	dpdv = rays.Divide(rays.Subtract(cp.B, cp.A), cp.V1-cp.V0)
+	if c.Type == RibbonCurve {
+		return rays.Multiply(normalizeVector(rays.Add(rays.Multiply(cp.sideA, 1-f), rays.Multiply(cp.sideB, f))), cp.widthAt(f)), dpdv
+	}
+	axis := rays.Add(cp.A, rays.Multiply(rays.Subtract(cp.B, cp.A), f))
+	return rays.Multiply(cross(cp.tangent, rays.Subtract(p, axis)), 2*math.Pi), dpdv
+}
+
+//pieceAtPoint returns the piece whose surface p lies closest to (-1 if none is near) and how far along it p lies, from 0 to 1
+func (c Curves) pieceAtPoint(p rays.Point) (piece int, f float32) {
+	best := float32(math.MaxFloat32)
+	piece = -1
+	c.tree.pointItems(p, func(i int) {
+		cp := c.pieces[i]
+		length := rays.Magnitude(rays.Subtract(cp.B, cp.A))
+		along := clampFloat(rays.DotProduct(rays.Subtract(p, cp.A), cp.tangent)/maxFloat(length, hitEpsilon), 0, 1)
+		axis := rays.Add(cp.A, rays.Multiply(rays.Subtract(cp.B, cp.A), along))
+		dist := rays.Magnitude(rays.Subtract(p, axis))
+		if c.Type == TubeCurve {
+			//distance from the tube wall rather than the axis
+			dist = absFloat(dist - cp.widthAt(along)/2)
+		}
+		if dist < best {
+			best, piece, f = dist, i, along
+		}
+	})
+	return piece, f
+}
+
+//perpendicular returns a unit direction at right angles to unit direction d
+func perpendicular(d rays.Point) rays.Point {
+	if absFloat(d.X) < 0.9 {
+		return normalizeVector(cross(d, rays.Point{X: 1}))
+	}
+	return normalizeVector(cross(d, rays.Point{Y: 1}))
+}
+
+//clampFloat returns v limited to the range [min,max]
+func clampFloat(v float32, min float32, max float32) float32 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
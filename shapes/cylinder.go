@@ -0,0 +1,144 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Cylinder represents a capped cylinder standing on Base (the center of its bottom cap) and extending Height along the Y axis.
+//Use an Instance to orient it along another axis.
+type Cylinder struct {
+	Base   rays.Point
+	Radius float32
+	Height float32
+	Color  rays.Point
+	Material
+}
+
+//Equals returns true if the 2 Intersectables are equivalent
+func (c Cylinder) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Cylinder:
+		compare := i.(Cylinder)
+		return c.Base.Equals(compare.Base) && c.Radius == compare.Radius && c.Height == compare.Height
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect tests the curved side as a quadratic in x and z, and each cap as a disk
+func (c Cylinder) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	return nearestHits(r, c.hitDistances(r)...)
+}
+
+//Intervals returns the span ray r spends inside the cylinder
+func (c Cylinder) Intervals(r rays.Ray) []Interval {
+	return pairIntervals(c.hitDistances(r))
+}
+
+//hitDistances returns the ray distances of every crossing of the cylinder's surface, in no particular order
+func (c Cylinder) hitDistances(r rays.Ray) []float32 {
+	o := rays.Subtract(r.Origin, c.Base)
+	d := r.Direction
+	res := make([]float32, 0, 4)
+
+	t0, t1 := solveQuadratic(d.X*d.X+d.Z*d.Z, 2*(o.X*d.X+o.Z*d.Z), o.X*o.X+o.Z*o.Z-c.Radius*c.Radius)
+	for _, t := range []float32{t0, t1} {
+		if y := o.Y + t*d.Y; y >= 0 && y <= c.Height {
+			res = append(res, t)
+		}
+	}
+
+	if d.Y != 0 {
+		for _, capY := range []float32{0, c.Height} {
+			t := (capY - o.Y) / d.Y
+			x, z := o.X+t*d.X, o.Z+t*d.Z
+			if x*x+z*z <= c.Radius*c.Radius {
+				res = append(res, t)
+			}
+		}
+	}
+	return res
+}
+
+//ColorAtPoint returns the color at a given point.
+func (c Cylinder) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return c.surfaceColor(c.Color, p, c.Base, c)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (c Cylinder) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return c.filteredColor(c.Color, p, c.Base, c, c.GeometricNormalAtPoint(p).Direction, r)
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
+func (c Cylinder) NormalAtPoint(p rays.Point) rays.Ray {
+	return c.shadingNormal(c.GeometricNormalAtPoint(p), c.Base, c)
+}
+
+//GeometricNormalAtPoint returns the inward normal of the side or cap nearest to point p
+func (c Cylinder) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	switch c.partAtPoint(p) {
+	case bottomCap:
+		return rays.Ray{Origin: p, Direction: rays.Point{Y: 1}}
+	case topCap:
+		return rays.Ray{Origin: p, Direction: rays.Point{Y: -1}}
+	default:
+		rel := rays.Subtract(p, c.Base)
+		return rays.Ray{Origin: p, Direction: normalizeVector(rays.Point{X: -rel.X, Z: -rel.Z})}
+	}
+}
+
+//UVAtPoint wraps u around the side with v running up it, caps are mapped as a square around the disk
+func (c Cylinder) UVAtPoint(p rays.Point) (u float32, v float32) {
+	rel := rays.Subtract(p, c.Base)
+	if c.partAtPoint(p) != side {
+		return 0.5 + rel.X/(2*c.Radius), 0.5 + rel.Z/(2*c.Radius)
+	}
+	return 0.5 + float32(math.Atan2(float64(rel.Z), float64(rel.X))/(2*math.Pi)), rel.Y / c.Height
+}
+
+//Bounds returns the box enclosing the cylinder
+func (c Cylinder) Bounds() BoundingBox {
+	return BoundingBox{
+		Min: rays.Point{X: c.Base.X - c.Radius, Y: c.Base.Y, Z: c.Base.Z - c.Radius},
+		Max: rays.Point{X: c.Base.X + c.Radius, Y: c.Base.Y + c.Height, Z: c.Base.Z + c.Radius}}
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (c Cylinder) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	if c.partAtPoint(p) != side {
+		return rays.Point{X: 1}, rays.Point{Z: 1}
+	}
+	rel := rays.Subtract(p, c.Base)
+	return rays.Point{X: -rel.Z, Z: rel.X}, rays.Point{Y: 1}
+}
+
+//capPart identifies which surface of a capped shape a point lies on
+type capPart int
+
+const (
+	side capPart = iota
+	bottomCap
+	topCap
+)
+
+func (c Cylinder) partAtPoint(p rays.Point) capPart {
+	rel := rays.Subtract(p, c.Base)
+	rho := float32(math.Sqrt(float64(rel.X*rel.X + rel.Z*rel.Z)))
+	return nearestPart(float32(math.Abs(float64(rho-c.Radius))), rel.Y, c.Height)
+}
+
+//nearestPart returns the part of a capped shape closest to a point sideDist from its curved side and at height y above its base
+func nearestPart(sideDist float32, y float32, height float32) capPart {
+	bottom := float32(math.Abs(float64(y)))
+	top := float32(math.Abs(float64(y - height)))
+	if bottom < sideDist && bottom <= top {
+		return bottomCap
+	}
+	if top < sideDist {
+		return topCap
+	}
+	return side
+}
@@ -0,0 +1,102 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Disk represents a flat circle of Radius around Center, facing along Normal.
+//Rays hit it from either side, while lighting treats Normal as pointing into the surface like the other shapes.
+type Disk struct {
+	Center rays.Point
+	Normal rays.Point
+	Radius float32
+	Color  rays.Point
+	Material
+}
+
+//Equals returns true if the 2 Intersectables are equivalent
+func (d Disk) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Disk:
+		compare := i.(Disk)
+		return d.Center.Equals(compare.Center) && d.Normal.Equals(compare.Normal) && d.Radius == compare.Radius
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect performs the ray disk intersection described here: https://www.scratchapixel.com/lessons/3d-basic-rendering/minimal-ray-tracer-rendering-simple-shapes/ray-plane-and-ray-disk-intersection
+func (d Disk) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	n := normalizeVector(d.Normal)
+	denom := rays.DotProduct(n, r.Direction)
+	if denom > -1e-6 && denom < 1e-6 {
+		return false, intersectPoint0, intersectPoint1
+	}
+
+	t := rays.DotProduct(rays.Subtract(d.Center, r.Origin), n) / denom
+	p := rays.Add(r.Origin, rays.Multiply(r.Direction, t))
+	if rays.Magnitude(rays.Subtract(p, d.Center)) > d.Radius {
+		return false, intersectPoint0, intersectPoint1
+	}
+	return nearestHits(r, t)
+}
+
+//ColorAtPoint returns the color at a given point.
+func (d Disk) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return d.surfaceColor(d.Color, p, d.Center, d)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (d Disk) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return d.filteredColor(d.Color, p, d.Center, d, normalizeVector(d.Normal), r)
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
+func (d Disk) NormalAtPoint(p rays.Point) rays.Ray {
+	return d.shadingNormal(d.GeometricNormalAtPoint(p), d.Center, d)
+}
+
+//GeometricNormalAtPoint returns the disk's normal, which is the same everywhere
+func (d Disk) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	return rays.Ray{Origin: p, Direction: normalizeVector(d.Normal)}
+}
+
+//UVAtPoint returns polar coordinates, u around the center and v out from it to the rim
+func (d Disk) UVAtPoint(p rays.Point) (u float32, v float32) {
+	tangent, bitangent := d.frame()
+	rel := rays.Subtract(p, d.Center)
+	x, y := rays.DotProduct(rel, tangent), rays.DotProduct(rel, bitangent)
+	u = 0.5 + float32(math.Atan2(float64(y), float64(x))/(2*math.Pi))
+	v = rays.Magnitude(rel) / d.Radius
+	return u, v
+}
+
+//Bounds returns the box enclosing the disk
+func (d Disk) Bounds() BoundingBox {
+	n := normalizeVector(d.Normal)
+	//the disk's extent along each axis is radius * sin of the angle between that axis and the normal
+	ext := rays.Point{
+		X: d.Radius * float32(math.Sqrt(math.Max(0, float64(1-n.X*n.X)))),
+		Y: d.Radius * float32(math.Sqrt(math.Max(0, float64(1-n.Y*n.Y)))),
+		Z: d.Radius * float32(math.Sqrt(math.Max(0, float64(1-n.Z*n.Z))))}
+	return BoundingBox{Min: rays.Subtract(d.Center, ext), Max: rays.Add(d.Center, ext)}
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (d Disk) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	rel := rays.Subtract(p, d.Center)
+	return cross(normalizeVector(d.Normal), rel), rel
+}
+
+//frame returns two unit vectors spanning the disk's plane
+func (d Disk) frame() (tangent rays.Point, bitangent rays.Point) {
+	n := normalizeVector(d.Normal)
+	helper := rays.Point{X: 1}
+	if math.Abs(float64(n.X)) > 0.9 {
+		helper = rays.Point{Y: 1}
+	}
+	tangent = normalizeVector(cross(helper, n))
+	return tangent, cross(n, tangent)
+}
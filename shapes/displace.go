@@ -0,0 +1,164 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/textures"
+)
+
+//Displacement describes real geometric detail added to a mesh: the mesh is split until its edges are short enough and every vertex is then
+//moved along the surface normal by the height read from Map.  Unlike a bump map, the silhouette and shadows change too.
+type Displacement struct {
+	Map   textures.Texture //height map, read from the luminance of the texture like a bump map.  Required.
+	Scale float32          //world units of displacement for a luminance of 1, negative values push the surface in
+
+	//EdgeLength is the longest edge left after splitting, in world units, or in pixels when ScreenDistance is set.  Zero or less splits nothing,
+	//displacing only the mesh's existing vertices.
+	EdgeLength float32
+	//Camera and ScreenDistance measure edges in pixels, as seen from Camera through a screen ScreenDistance away where one unit is one pixel
+	//(the default camera's screen is 1000 units away).  Nearby edges are then split finer than distant ones.  Zero measures edges in world units.
+	Camera         rays.Point
+	ScreenDistance float32
+	//MaxLevels is the most times a triangle is split, bounding the size of the mesh.  Zero uses a default of 8.
+	MaxLevels int
+	//CreaseAngle is used to rebuild the normals after displacement, see SmoothNormals.  Zero smooths across every edge.
+	CreaseAngle float32
+}
+
+//defaultDisplacementLevels is how many times a triangle may be split when Displacement.MaxLevels is zero
+const defaultDisplacementLevels = 8
+
+//Displace returns a new mesh with displacement d applied.  Triangles are split where their edges are longer than a positive d.EdgeLength,
+//each split edge getting a midpoint shared by the triangles on both sides so no cracks open.  The vertices are then moved along the normal
+//averaged over every face touching their position, so vertices duplicated along hard edges or texture seams move together.  Finally the normals
+//are rebuilt from the displaced surface, so weld the mesh first for normals that are smooth across its triangles.
+//Since the displaced mesh is built from scratch its bounds and hierarchy enclose the displaced vertices.
+func (m Mesh) Displace(d Displacement) Mesh {
+	levels := d.MaxLevels
+	if levels == 0 {
+		levels = defaultDisplacementLevels
+	}
+	if d.EdgeLength <= 0 {
+		levels = 0
+	}
+	for i := 0; i < levels; i++ {
+		split, ok := m.splitLongEdges(d)
+		if !ok {
+			break
+		}
+		m = split
+	}
+
+	//outward normals, averaged over the faces at each position
+	outward := map[rays.Point]rays.Point{}
+	for i, f := range m.Faces {
+		if m.faceNormals[i] == (rays.Point{}) {
+			continue
+		}
+		e1 := rays.Subtract(m.Vertices[f[1]], m.Vertices[f[0]])
+		e2 := rays.Subtract(m.Vertices[f[2]], m.Vertices[f[0]])
+		area := cross(e1, e2)
+		for _, v := range f {
+			outward[m.Vertices[v]] = rays.Add(outward[m.Vertices[v]], area)
+		}
+	}
+
+	b := newMeshBuilder(m, false)
+	for v, p := range m.Vertices {
+		b.addVertex(v)
+		s := textures.Sample{P: p}
+		if m.UVs != nil {
+			s.U, s.V = m.UVs[v][0], m.UVs[v][1]
+		}
+		if m.Colors != nil {
+			s.VertexColor = m.Colors[v]
+		}
+		height := textures.Luminance(d.Map.ColorAt(s)) * d.Scale
+		b.vertices[v] = rays.Add(p, rays.Multiply(normalizeVector(outward[p]), height))
+	}
+	b.faces = m.Faces
+
+	crease := d.CreaseAngle
+	if crease == 0 {
+		crease = math.Pi
+	}
+	return b.build().SmoothNormals(crease)
+}
+
+//splitLongEdges splits every edge longer than the displacement's edge length at its midpoint, dividing each triangle into two, three or four
+//so the new vertices are shared with its neighbours.  ok is false if no edge needed splitting.
+func (m Mesh) splitLongEdges(d Displacement) (split Mesh, ok bool) {
+	edgeKey := func(a int, b int) [2]int {
+		if a > b {
+			a, b = b, a
+		}
+		return [2]int{a, b}
+	}
+	midpoints := map[[2]int]int{}
+	w := newMeshBuilder(m, false)
+	for v := range m.Vertices {
+		w.addVertex(v)
+	}
+	//midpoint returns the new vertex on edge a-b, or -1 if the edge is short enough to keep
+	midpoint := func(a int, b int) int {
+		key := edgeKey(a, b)
+		if i, ok := midpoints[key]; ok {
+			return i
+		}
+		i := -1
+		if d.edgeLength(m.Vertices[key[0]], m.Vertices[key[1]]) > d.EdgeLength {
+			i = w.addMidpoint(key[0], key[1])
+		}
+		midpoints[key] = i
+		return i
+	}
+
+	for _, f := range m.Faces {
+		mids := [3]int{midpoint(f[0], f[1]), midpoint(f[1], f[2]), midpoint(f[2], f[0])}
+		count := 0
+		for _, mid := range mids {
+			if mid >= 0 {
+				count++
+			}
+		}
+		if count == 0 {
+			w.faces = append(w.faces, f)
+			continue
+		}
+		ok = true
+
+		//rotate the corners so the cases below only need handling once: with one split it is edge 0-1, with two the unsplit one is edge 2-0
+		for r := 0; r < 3; r++ {
+			if (count == 1 && mids[0] >= 0) || (count == 2 && mids[2] < 0) || count == 3 {
+				break
+			}
+			f = [3]int{f[1], f[2], f[0]}
+			mids = [3]int{mids[1], mids[2], mids[0]}
+		}
+		a, b, c, ab, bc, ca := f[0], f[1], f[2], mids[0], mids[1], mids[2]
+		switch count {
+		case 1:
+			w.faces = append(w.faces, [3]int{a, ab, c}, [3]int{ab, b, c})
+		case 2:
+			w.faces = append(w.faces, [3]int{ab, b, bc}, [3]int{a, ab, bc}, [3]int{a, bc, c})
+		default:
+			w.faces = append(w.faces, [3]int{a, ab, ca}, [3]int{ab, b, bc}, [3]int{ca, bc, c}, [3]int{ab, bc, ca})
+		}
+	}
+	if !ok {
+		return m, false
+	}
+	return w.build(), true
+}
+
+//edgeLength returns the length of edge a-b in world units, or in pixels if the displacement measures edges in screen space
+func (d Displacement) edgeLength(a rays.Point, b rays.Point) float32 {
+	length := rays.Magnitude(rays.Subtract(b, a))
+	if d.ScreenDistance == 0 {
+		return length
+	}
+	mid := rays.Multiply(rays.Add(a, b), 0.5)
+	distance := maxFloat(rays.Magnitude(rays.Subtract(mid, d.Camera)), hitEpsilon)
+	return length * d.ScreenDistance / distance
+}
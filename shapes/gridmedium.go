@@ -0,0 +1,223 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//DistanceSampler is implemented by media whose density varies from place to place.  The light they scatter is gathered at points picked by
+//SampleDistance, so samples land where the medium is dense rather than at even steps along the ray.
+type DistanceSampler interface {
+	//SampleDistance picks the distance along r between t0 and t1 where light travelling along it next interacts with the medium, using random
+	//for numbers in [0,1).  albedo is the fraction of the light interacting there that is scattered rather than absorbed, per color channel.
+	//ok is false if the light gets past t1 without interacting.  r's direction must be normalized.
+	SampleDistance(r rays.Ray, t0 float32, t1 float32, random func() float32) (t float32, albedo rays.Point, ok bool)
+}
+
+//GridMedium is a medium whose density varies through a box, such as smoke or a cloud, given by a 3d grid of density samples.  The box spans
+//Size from Origin, each sample sits at the center of its grid cell and densities between them are interpolated trilinearly, with no medium
+//outside the box.  Absorption and Scattering give the fraction of light absorbed and scattered per unit of distance where the density is 1.
+//Transmittance is estimated by ratio tracking and interactions are found by delta tracking, both unbiased, so the medium shows fine noise
+//rather than banding.  Density data lives in a slice, so copies of a GridMedium share it.
+type GridMedium struct {
+	Origin     rays.Point
+	Size       rays.Point
+	Absorption rays.Point
+	Scattering rays.Point
+	G          float32 //Henyey-Greenstein asymmetry of the scattering, see HomogeneousMedium
+	densities  []float32
+	counts     [3]int //samples along each axis
+	maxDensity float32
+}
+
+//NewGridMedium creates a medium from densities, given x fastest, then y, then z, for a grid of countX*countY*countZ samples spanning size
+//from origin.  Set Absorption and Scattering to make it visible.  Returns an error if the number of densities does not match the grid or
+//any is negative, infinite or NaN.
+func NewGridMedium(densities []float32, countX int, countY int, countZ int, origin rays.Point, size rays.Point) (GridMedium, error) {
+	if countX < 1 || countY < 1 || countZ < 1 {
+		return GridMedium{}, errors.New("density grid needs at least 1 sample along each axis")
+	}
+	if len(densities) != countX*countY*countZ {
+		return GridMedium{}, errors.New("density grid has the wrong number of samples for its size")
+	}
+	g := GridMedium{Origin: origin, Size: size, densities: densities, counts: [3]int{countX, countY, countZ}}
+	for _, d := range densities {
+		//an infinite density would leave delta and ratio tracking stepping nowhere
+		if d < 0 || math.IsNaN(float64(d)) || math.IsInf(float64(d), 0) {
+			return GridMedium{}, errors.New("density grid samples must be finite and not negative")
+		}
+		g.maxDensity = maxFloat(g.maxDensity, d)
+	}
+	return g, nil
+}
+
+//NewGridMediumFromFunc creates a medium by sampling density at the center of every cell of a countX*countY*countZ grid spanning size from
+//origin.  Procedural noise makes good smoke and clouds, such as textures.FBM faded towards the edges of the box.
+func NewGridMediumFromFunc(density func(p rays.Point) float32, countX int, countY int, countZ int, origin rays.Point, size rays.Point) (GridMedium, error) {
+	if countX < 1 || countY < 1 || countZ < 1 {
+		return GridMedium{}, errors.New("density grid needs at least 1 sample along each axis")
+	}
+	densities := make([]float32, 0, countX*countY*countZ)
+	for z := 0; z < countZ; z++ {
+		for y := 0; y < countY; y++ {
+			for x := 0; x < countX; x++ {
+				p := rays.Point{
+					X: origin.X + size.X*(float32(x)+0.5)/float32(countX),
+					Y: origin.Y + size.Y*(float32(y)+0.5)/float32(countY),
+					Z: origin.Z + size.Z*(float32(z)+0.5)/float32(countZ),
+				}
+				densities = append(densities, maxFloat(density(p), 0))
+			}
+		}
+	}
+	return NewGridMedium(densities, countX, countY, countZ, origin, size)
+}
+
+//Density returns the density at p, interpolated between the grid samples around it, or 0 outside the box
+func (g GridMedium) Density(p rays.Point) float32 {
+	rel := rays.Subtract(p, g.Origin)
+	grid := [3]float32{rel.X / g.Size.X, rel.Y / g.Size.Y, rel.Z / g.Size.Z}
+	var low, high [3]int
+	var f [3]float32
+	for i, c := range grid {
+		if c < 0 || c > 1 {
+			return 0
+		}
+		//sample centers lie half a cell in from the box's faces, densities beyond the outermost ones are held flat
+		s := c*float32(g.counts[i]) - 0.5
+		low[i] = clampInt(int(math.Floor(float64(s))), 0, g.counts[i]-1)
+		high[i] = minInt(low[i]+1, g.counts[i]-1)
+		f[i] = clampFloat(s-float32(low[i]), 0, 1)
+	}
+
+	sample := func(x int, y int, z int) float32 {
+		return g.densities[(z*g.counts[1]+y)*g.counts[0]+x]
+	}
+	lerp := func(a float32, b float32, f float32) float32 {
+		return a + (b-a)*f
+	}
+	d00 := lerp(sample(low[0], low[1], low[2]), sample(high[0], low[1], low[2]), f[0])
+	d10 := lerp(sample(low[0], high[1], low[2]), sample(high[0], high[1], low[2]), f[0])
+	d01 := lerp(sample(low[0], low[1], high[2]), sample(high[0], low[1], high[2]), f[0])
+	d11 := lerp(sample(low[0], high[1], high[2]), sample(high[0], high[1], high[2]), f[0])
+	return lerp(lerp(d00, d10, f[1]), lerp(d01, d11, f[1]), f[2])
+}
+
+//Coefficients returns the absorption and scattering at p, scaled by the density there
+func (g GridMedium) Coefficients(p rays.Point) (absorption rays.Point, scattering rays.Point) {
+	d := g.Density(p)
+	return rays.Multiply(g.Absorption, d), rays.Multiply(g.Scattering, d)
+}
+
+//Transmittance estimates the fraction of light left after travelling from t0 to t1 by ratio tracking: it steps through the box as if the
+//medium were everywhere as dense as its densest sample, and at each step keeps the fraction of light the real medium there would let through.
+//Very dim light is ended at random, with the light that survives strengthened to make up for it.
+func (g GridMedium) Transmittance(r rays.Ray, t0 float32, t1 float32) rays.Point {
+	transmittance := rays.Point{X: 1, Y: 1, Z: 1}
+	t0, t1, ok := g.clip(r, t0, t1)
+	majorant := g.majorant()
+	if !ok || majorant == 0 {
+		return transmittance
+	}
+
+	random := newRayRandom(r, t0)
+	for t := t0; ; {
+		t -= float32(math.Log(float64(1-random()))) / majorant
+		if t >= t1 {
+			return transmittance
+		}
+		a, s := g.Coefficients(rays.Add(r.Origin, rays.Multiply(r.Direction, t)))
+		extinction := rays.Add(a, s)
+		transmittance = multiplyColors(transmittance, rays.Subtract(rays.Point{X: 1, Y: 1, Z: 1}, rays.Multiply(extinction, 1/majorant)))
+
+		if m := maxFloat(transmittance.X, maxFloat(transmittance.Y, transmittance.Z)); m < 0.1 {
+			if random() < 0.5 {
+				return rays.Point{}
+			}
+			transmittance = rays.Multiply(transmittance, 2)
+		}
+	}
+}
+
+//SampleDistance picks where light next interacts with the medium by delta tracking: it steps through the box as if the medium were everywhere
+//as dense as its densest sample, and at each step interacts with the chance that the real medium is there.  Steps are taken by the color
+//channel with the strongest extinction, so media that absorb or scatter colors differently are approximated.
+func (g GridMedium) SampleDistance(r rays.Ray, t0 float32, t1 float32, random func() float32) (t float32, albedo rays.Point, ok bool) {
+	t0, t1, ok = g.clip(r, t0, t1)
+	majorant := g.majorant()
+	if !ok || majorant == 0 {
+		return 0, albedo, false
+	}
+
+	extinction := g.extinction()
+	for t = t0; ; {
+		t -= float32(math.Log(float64(1-random()))) / majorant
+		if t >= t1 {
+			return 0, albedo, false
+		}
+		if random()*g.maxDensity < g.Density(rays.Add(r.Origin, rays.Multiply(r.Direction, t))) {
+			return t, rays.Multiply(g.Scattering, 1/extinction), true
+		}
+	}
+}
+
+//Phase returns the Henyey-Greenstein phase function for the medium's asymmetry
+func (g GridMedium) Phase(cosTheta float32) float32 {
+	return HenyeyGreenstein(cosTheta, g.G)
+}
+
+//Bounds returns the box the medium fills
+func (g GridMedium) Bounds() BoundingBox {
+	box := emptyBounds()
+	return box.Extend(g.Origin).Extend(rays.Add(g.Origin, g.Size))
+}
+
+//extinction returns the strongest channel of the absorption and scattering together, where the density is 1
+func (g GridMedium) extinction() float32 {
+	e := rays.Add(g.Absorption, g.Scattering)
+	return maxFloat(e.X, maxFloat(e.Y, e.Z))
+}
+
+//majorant returns the highest extinction anywhere in the medium
+func (g GridMedium) majorant() float32 {
+	return g.extinction() * g.maxDensity
+}
+
+//clip narrows the span from t0 to t1 along r to the part inside the medium's box.  ok is false if none of it is.
+func (g GridMedium) clip(r rays.Ray, t0 float32, t1 float32) (float32, float32, bool) {
+	tNear, tFar, hit := g.Bounds().Intersect(r)
+	t0, t1 = maxFloat(t0, tNear), minFloat(t1, tFar)
+	return t0, t1, hit && t0 < t1
+}
+
+//newRayRandom returns a generator of numbers in [0,1) seeded from the ray and a distance along it, so the same ray gives the same noise
+func newRayRandom(r rays.Ray, t float32) func() float32 {
+	state := uint32(rayJitter(r)*(1<<24)) ^ math.Float32bits(t)*2654435761 | 1
+	return func() float32 {
+		//xorshift32
+		state ^= state << 13
+		state ^= state >> 17
+		state ^= state << 5
+		return float32(state>>8) / (1 << 24)
+	}
+}
+
+//sampledScatter is singleScatter for media that pick their own sample points, gathering light at the interactions found by delta tracking
+func sampledScatter(ds DistanceSampler, m Medium, r rays.Ray, t0 float32, t1 float32, lights []rays.Point) (scattered rays.Point, transmittance rays.Point) {
+	random := newRayRandom(r, t1)
+	for i := 0; i < scatterSteps; i++ {
+		t, albedo, ok := ds.SampleDistance(r, t0, t1, random)
+		if !ok {
+			continue
+		}
+		p := rays.Add(r.Origin, rays.Multiply(r.Direction, t))
+		for _, light := range lights {
+			toLight := normalizeVector(rays.Subtract(light, p))
+			strength := m.Phase(rays.DotProduct(toLight, r.Direction)) * lightIntensity / scatterSteps
+			scattered = rays.Add(scattered, rays.Multiply(multiplyColors(lightTransmittance(p, light, nil), albedo), strength))
+		}
+	}
+	return scattered, m.Transmittance(r, t0, t1)
+}
@@ -0,0 +1,90 @@
+package shapes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//testGridMedium returns a box of constant density 1, 10 across in x and y and 5 deep in z, whose extinction differs by color channel
+func testGridMedium(t *testing.T) GridMedium {
+	densities := []float32{1, 1, 1, 1, 1, 1, 1, 1}
+	g, err := NewGridMedium(densities, 2, 2, 2, rays.Point{}, rays.Point{X: 10, Y: 10, Z: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Absorption = rays.Point{X: 0.05, Y: 0.1, Z: 0.2}
+	g.Scattering = rays.Point{X: 0.05, Y: 0.1, Z: 0.1}
+	return g
+}
+
+//testGridRays returns n rays straight through the depth of testGridMedium, spread over its face
+func testGridRays(n int) []rays.Ray {
+	rs := make([]rays.Ray, n)
+	for i := range rs {
+		x, y := float32(i%64)/64*10, float32(i/64)/float32(n/64+1)*10
+		rs[i] = rays.Ray{Origin: rays.Point{X: x, Y: y, Z: -1}, Direction: rays.Point{Z: 1}}
+	}
+	return rs
+}
+
+func TestGridMediumTransmittance(t *testing.T) {
+	g := testGridMedium(t)
+	const n = 4096
+	var sum rays.Point
+	for _, r := range testGridRays(n) {
+		sum = rays.Add(sum, g.Transmittance(r, 0, 100))
+	}
+	mean := rays.Multiply(sum, 1.0/n)
+
+	//5 units of extinction 0.1, 0.2 and 0.3
+	want := expColor(rays.Point{X: -0.5, Y: -1, Z: -1.5})
+	if !closeTo(mean, want, 0.03) {
+		t.Errorf("mean transmittance = %v, want %v", mean, want)
+	}
+
+	//stopping halfway through only lets half the medium dim the light
+	sum = rays.Point{}
+	for _, r := range testGridRays(n) {
+		sum = rays.Add(sum, g.Transmittance(r, 0, 3.5))
+	}
+	mean = rays.Multiply(sum, 1.0/n)
+	if want := expColor(rays.Point{X: -0.25, Y: -0.5, Z: -0.75}); !closeTo(mean, want, 0.03) {
+		t.Errorf("mean transmittance halfway = %v, want %v", mean, want)
+	}
+}
+
+func TestGridMediumSampleDistance(t *testing.T) {
+	g := testGridMedium(t)
+	const n = 4096
+	interacted := 0
+	for i, r := range testGridRays(n) {
+		random := newRayRandom(r, float32(i))
+		d, albedo, ok := g.SampleDistance(r, 0, 100, random)
+		if !ok {
+			continue
+		}
+		interacted++
+		if d < 1 || d > 6 {
+			t.Fatalf("interaction at %v, outside the medium from 1 to 6", d)
+		}
+		if want := (rays.Point{X: 0.05 / 0.3, Y: 0.1 / 0.3, Z: 0.1 / 0.3}); !closeTo(albedo, want, 1e-6) {
+			t.Fatalf("albedo = %v, want %v", albedo, want)
+		}
+	}
+
+	//steps are taken by the strongest channel, extinction 0.3 over 5 units
+	got := float64(interacted) / n
+	if want := 1 - math.Exp(-1.5); math.Abs(got-want) > 0.03 {
+		t.Errorf("fraction of rays interacting = %v, want %v", got, want)
+	}
+}
+
+func TestNewGridMediumRejectsBadSamples(t *testing.T) {
+	for _, d := range []float32{-1, float32(math.NaN()), float32(math.Inf(1))} {
+		if _, err := NewGridMedium([]float32{1, d}, 2, 1, 1, rays.Point{}, rays.Point{X: 1, Y: 1, Z: 1}); err == nil {
+			t.Errorf("NewGridMedium accepted a sample of %v", d)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/textures"
+)
+
+//Heightfield represents terrain over a rectangular grid of height samples.  The grid spans Size.X along X and Size.Z along Z from Origin,
+//and a sample of 1 lies Size.Y along Y from Origin, so make Size.Y negative to raise the terrain towards -Y (up in the default scene).
+//Each grid cell is split into two triangles, and normals are interpolated between the grid points so the terrain shades smoothly.
+//Sample data lives in slices, so copies of a Heightfield share it.
+type Heightfield struct {
+	Origin rays.Point
+	Size   rays.Point
+	Color  rays.Point
+	Material
+	heights []float32 //(columns+1)*(rows+1) samples, row by row along Z
+	columns int       //cells along X
+	rows    int       //cells along Z
+	cellMin []float32 //lowest and highest sample at the corners of each cell, so rays passing over or under it can skip it
+	cellMax []float32
+}
+
+//NewHeightfield creates terrain from heights, given as rows of samples along X with one row per step along Z.
+//Returns an error if there are fewer than two rows or columns of samples, or the rows are not all the same length.
+func NewHeightfield(heights [][]float32, origin rays.Point, size rays.Point, color rays.Point) (Heightfield, error) {
+	if len(heights) < 2 || len(heights[0]) < 2 {
+		return Heightfield{}, errors.New("heightfield needs at least 2x2 samples")
+	}
+	h := Heightfield{Origin: origin, Size: size, Color: color, columns: len(heights[0]) - 1, rows: len(heights) - 1}
+	h.heights = make([]float32, 0, len(heights)*len(heights[0]))
+	for _, row := range heights {
+		if len(row) != h.columns+1 {
+			return Heightfield{}, errors.New("heightfield rows must all have the same number of samples")
+		}
+		h.heights = append(h.heights, row...)
+	}
+
+	h.cellMin = make([]float32, h.columns*h.rows)
+	h.cellMax = make([]float32, h.columns*h.rows)
+	for iz := 0; iz < h.rows; iz++ {
+		for ix := 0; ix < h.columns; ix++ {
+			h00, h10, h01, h11 := h.cellCorners(ix, iz)
+			h.cellMin[iz*h.columns+ix] = minFloat(minFloat(h00, h10), minFloat(h01, h11))
+			h.cellMax[iz*h.columns+ix] = maxFloat(maxFloat(h00, h10), maxFloat(h01, h11))
+		}
+	}
+	return h, nil
+}
+
+//NewHeightfieldFromImage creates terrain from the brightness of each pixel in a PNG or JPEG file, black at height 0 and white at height 1.
+//Image columns run along X and rows along Z.
+func NewHeightfieldFromImage(path string, origin rays.Point, size rays.Point, color rays.Point) (Heightfield, error) {
+	b, err := textures.LoadBitmap(path)
+	if err != nil {
+		return Heightfield{}, err
+	}
+	heights := make([][]float32, b.Height)
+	for y := range heights {
+		heights[y] = make([]float32, b.Width)
+		for x := range heights[y] {
+			heights[y][x] = textures.Luminance(b.Texel(x, y))
+		}
+	}
+	return NewHeightfield(heights, origin, size, color)
+}
+
+//NewHeightfieldFromFunc creates terrain by sampling height, given the fraction (0-1) of the way across the grid along X and Z, at every corner
+//of a grid with the given number of cells.  Procedural noise such as textures.FBM makes a good height function.
+func NewHeightfieldFromFunc(height func(u float32, v float32) float32, columns int, rows int, origin rays.Point, size rays.Point, color rays.Point) (Heightfield, error) {
+	if columns < 1 || rows < 1 {
+		return Heightfield{}, errors.New("heightfield needs at least 2x2 samples")
+	}
+	heights := make([][]float32, rows+1)
+	for iz := range heights {
+		heights[iz] = make([]float32, columns+1)
+		for ix := range heights[iz] {
+			heights[iz][ix] = height(float32(ix)/float32(columns), float32(iz)/float32(rows))
+		}
+	}
+	return NewHeightfield(heights, origin, size, color)
+}
+
+//Equals returns true if the 2 Intersectables are equivalent.  Heightfields are equal when they share the same sample data.
+func (h Heightfield) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Heightfield:
+		compare := i.(Heightfield)
+		if len(h.heights) == 0 || len(compare.heights) == 0 {
+			return len(h.heights) == len(compare.heights)
+		}
+		return &h.heights[0] == &compare.heights[0] && h.Origin.Equals(compare.Origin) && h.Size.Equals(compare.Size)
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect walks the grid cells under the ray with a 2d DDA (see: http://www.cse.yorku.ca/~amana/research/grid.pdf), skipping cells
+//the ray passes over or under, and tests the two triangles of the rest.  The surface is only hit once, so both points returned are the same.
+func (h Heightfield) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	tNear, tFar, hit := h.Bounds().Intersect(r)
+	if h.columns == 0 || !hit {
+		return false, intersectPoint0, intersectPoint1
+	}
+	t := maxFloat(tNear, 0)
+
+	//the ray in grid coordinates, where each cell is one unit across
+	gx, dgx := (r.Origin.X-h.Origin.X)/h.Size.X*float32(h.columns), r.Direction.X/h.Size.X*float32(h.columns)
+	gz, dgz := (r.Origin.Z-h.Origin.Z)/h.Size.Z*float32(h.rows), r.Direction.Z/h.Size.Z*float32(h.rows)
+	ix := clampInt(int(math.Floor(float64(gx+t*dgx))), 0, h.columns-1)
+	iz := clampInt(int(math.Floor(float64(gz+t*dgz))), 0, h.rows-1)
+	stepX, tMaxX, tDeltaX := ddaAxis(gx, dgx, ix)
+	stepZ, tMaxZ, tDeltaZ := ddaAxis(gz, dgz, iz)
+
+	for ix >= 0 && ix < h.columns && iz >= 0 && iz < h.rows && t <= tFar {
+		tExit := minFloat(minFloat(tMaxX, tMaxZ), tFar)
+		if h.rayOverlapsCell(r, ix, iz, t, tExit) {
+			if hitT, ok := h.intersectCell(r, ix, iz); ok {
+				p := rays.Add(r.Origin, rays.Multiply(r.Direction, hitT))
+				return true, p, p
+			}
+		}
+		if tMaxX < tMaxZ {
+			ix, t, tMaxX = ix+stepX, tMaxX, tMaxX+tDeltaX
+		} else {
+			iz, t, tMaxZ = iz+stepZ, tMaxZ, tMaxZ+tDeltaZ
+		}
+	}
+	return false, intersectPoint0, intersectPoint1
+}
+
+//ColorAtPoint returns the color at a given point.
+func (h Heightfield) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return h.surfaceColor(h.Color, p, h.Origin, h)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (h Heightfield) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return h.filteredColor(h.Color, p, h.Origin, h, h.GeometricNormalAtPoint(p).Direction, r)
+}
+
+//NormalAtPoint returns the smooth normal interpolated from the grid points around p, perturbed by any normal or bump map
+func (h Heightfield) NormalAtPoint(p rays.Point) rays.Ray {
+	ix, iz, fx, fz := h.cellAtPoint(p)
+	n00, n10 := h.sampleNormal(ix, iz), h.sampleNormal(ix+1, iz)
+	n01, n11 := h.sampleNormal(ix, iz+1), h.sampleNormal(ix+1, iz+1)
+	n := textures.Lerp(textures.Lerp(n00, n10, fx), textures.Lerp(n01, n11, fx), fz)
+	smooth := rays.Ray{Origin: p, Direction: rays.Multiply(normalizeVector(n), -1)}
+	return h.shadingNormal(smooth, h.Origin, h)
+}
+
+//GeometricNormalAtPoint returns the inward normal of the flat triangle point p lies on
+func (h Heightfield) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	dhdx, dhdz := h.slopeAtPoint(p)
+	return rays.Ray{Origin: p, Direction: rays.Multiply(h.outward(dhdx, dhdz), -1)}
+}
+
+//UVAtPoint returns how far across the grid p lies along X and Z, matching the pixel layout of an image the terrain was loaded from
+func (h Heightfield) UVAtPoint(p rays.Point) (u float32, v float32) {
+	return (p.X - h.Origin.X) / h.Size.X, (p.Z - h.Origin.Z) / h.Size.Z
+}
+
+//Bounds returns the box enclosing the grid, from its lowest to its highest sample
+func (h Heightfield) Bounds() BoundingBox {
+	if h.columns == 0 {
+		return emptyBounds()
+	}
+	lo, hi := h.cellMin[0], h.cellMax[0]
+	for i := range h.cellMin {
+		lo, hi = minFloat(lo, h.cellMin[i]), maxFloat(hi, h.cellMax[i])
+	}
+	low := rays.Add(h.Origin, rays.Point{Y: h.Size.Y * lo})
+	return BoundingBox{Min: low, Max: low}.Extend(rays.Add(h.Origin, rays.Point{X: h.Size.X, Y: h.Size.Y * hi, Z: h.Size.Z}))
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (h Heightfield) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	dhdx, dhdz := h.slopeAtPoint(p)
+	return rays.Point{X: h.Size.X, Y: dhdx * h.Size.X}, rays.Point{Y: dhdz * h.Size.Z, Z: h.Size.Z}
+}
+
+//ddaAxis returns the direction to step through cells along one axis, the ray distance to the first cell boundary and the distance between boundaries
+func ddaAxis(origin float32, direction float32, cell int) (step int, tMax float32, tDelta float32) {
+	switch {
+	case direction > 0:
+		return 1, (float32(cell+1) - origin) / direction, 1 / direction
+	case direction < 0:
+		return -1, (float32(cell) - origin) / direction, -1 / direction
+	default:
+		return 0, float32(math.Inf(1)), float32(math.Inf(1))
+	}
+}
+
+//rayOverlapsCell returns true if the heights the ray passes through between tEnter and tExit overlap the heights in cell ix, iz
+func (h Heightfield) rayOverlapsCell(r rays.Ray, ix int, iz int, tEnter float32, tExit float32) bool {
+	y0 := (r.Origin.Y + r.Direction.Y*tEnter - h.Origin.Y) / h.Size.Y
+	y1 := (r.Origin.Y + r.Direction.Y*tExit - h.Origin.Y) / h.Size.Y
+	const slack = 1e-4
+	c := iz*h.columns + ix
+	return minFloat(y0, y1) <= h.cellMax[c]+slack && maxFloat(y0, y1) >= h.cellMin[c]-slack
+}
+
+//intersectCell returns the ray distance to the nearest of the two triangles in cell ix, iz
+func (h Heightfield) intersectCell(r rays.Ray, ix int, iz int) (float32, bool) {
+	v00, v10 := h.vertex(ix, iz), h.vertex(ix+1, iz)
+	v01, v11 := h.vertex(ix, iz+1), h.vertex(ix+1, iz+1)
+	t0, _, _, hit0 := intersectTriangle(r, v00, v10, v11)
+	t1, _, _, hit1 := intersectTriangle(r, v00, v11, v01)
+	switch {
+	case hit0 && hit1:
+		return minFloat(t0, t1), true
+	case hit0:
+		return t0, true
+	default:
+		return t1, hit1
+	}
+}
+
+//cellAtPoint returns the cell under point p and how far across it (0-1) p lies along X and Z
+func (h Heightfield) cellAtPoint(p rays.Point) (ix int, iz int, fx float32, fz float32) {
+	gx := (p.X - h.Origin.X) / h.Size.X * float32(h.columns)
+	gz := (p.Z - h.Origin.Z) / h.Size.Z * float32(h.rows)
+	ix = clampInt(int(math.Floor(float64(gx))), 0, h.columns-1)
+	iz = clampInt(int(math.Floor(float64(gz))), 0, h.rows-1)
+	return ix, iz, clampUnit(gx - float32(ix)), clampUnit(gz - float32(iz))
+}
+
+//slopeAtPoint returns the world space rate of change in height along X and Z of the triangle point p lies on
+func (h Heightfield) slopeAtPoint(p rays.Point) (dhdx float32, dhdz float32) {
+	ix, iz, fx, fz := h.cellAtPoint(p)
+	h00, h10, h01, h11 := h.cellCorners(ix, iz)
+	dx, dz := h.Size.X/float32(h.columns), h.Size.Z/float32(h.rows)
+	if fx >= fz {
+		return (h10 - h00) * h.Size.Y / dx, (h11 - h10) * h.Size.Y / dz
+	}
+	return (h11 - h01) * h.Size.Y / dx, (h01 - h00) * h.Size.Y / dz
+}
+
+//sampleNormal returns the outward normal at grid point ix, iz, from the slope between its neighbours
+func (h Heightfield) sampleNormal(ix int, iz int) rays.Point {
+	x0, x1 := maxInt(ix-1, 0), minInt(ix+1, h.columns)
+	z0, z1 := maxInt(iz-1, 0), minInt(iz+1, h.rows)
+	dx, dz := h.Size.X/float32(h.columns), h.Size.Z/float32(h.rows)
+	dhdx := (h.sample(x1, iz) - h.sample(x0, iz)) * h.Size.Y / (float32(x1-x0) * dx)
+	dhdz := (h.sample(ix, z1) - h.sample(ix, z0)) * h.Size.Y / (float32(z1-z0) * dz)
+	return h.outward(dhdx, dhdz)
+}
+
+//outward returns the unit normal pointing out of the terrain, for a surface whose height changes by dhdx along X and dhdz along Z
+func (h Heightfield) outward(dhdx float32, dhdz float32) rays.Point {
+	n := normalizeVector(rays.Point{X: -dhdx, Y: 1, Z: -dhdz})
+	if h.Size.Y < 0 {
+		return rays.Multiply(n, -1)
+	}
+	return n
+}
+
+func (h Heightfield) sample(ix int, iz int) float32 {
+	return h.heights[iz*(h.columns+1)+ix]
+}
+
+func (h Heightfield) cellCorners(ix int, iz int) (h00 float32, h10 float32, h01 float32, h11 float32) {
+	return h.sample(ix, iz), h.sample(ix+1, iz), h.sample(ix, iz+1), h.sample(ix+1, iz+1)
+}
+
+func (h Heightfield) vertex(ix int, iz int) rays.Point {
+	return rays.Add(h.Origin, rays.Point{
+		X: h.Size.X * float32(ix) / float32(h.columns),
+		Y: h.Size.Y * h.sample(ix, iz),
+		Z: h.Size.Z * float32(iz) / float32(h.rows)})
+}
+
+func clampInt(v int, min int, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,114 @@
+package shapes
+
+import (
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Instance places a shape in the world through an affine transform.  The wrapped shape is defined in its own object space
+//and is shared rather than copied, so one mesh can be placed thousands of times for the cost of two matrices each.
+//Wrap instances in lighting (not the other way around) so lights stay in world space.
+//Reflections are traced in the inner shape's object space, so reflective shapes should be placed directly rather than instanced.
+type Instance struct {
+	Inner     Intersectable
+	transform rays.Matrix4
+	inverse   rays.Matrix4
+}
+
+//NewInstance creates an instance of inner placed by transform.  Precalculates the inverse transform, so transform must be invertible.
+func NewInstance(inner Intersectable, transform rays.Matrix4) (Instance, error) {
+	inverse, err := transform.Inverse()
+	if err != nil {
+		return Instance{}, err
+	}
+	return Instance{Inner: inner, transform: transform, inverse: inverse}, nil
+}
+
+//Transform returns the object to world transform of the instance
+func (in Instance) Transform() rays.Matrix4 {
+	return in.transform
+}
+
+//Equals returns true if the 2 Intersectables are equivalent
+func (in Instance) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Instance:
+		compare := i.(Instance)
+		return in.transform == compare.transform && in.Inner.Equals(compare.Inner)
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect moves the ray into object space, intersects the inner shape there and moves the hit points back into the world
+func (in Instance) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	do, p0, p1 := in.Inner.DoesRayIntersect(in.objectRay(r))
+	if !do {
+		return false, intersectPoint0, intersectPoint1
+	}
+	return true, in.transform.TransformPoint(p0), in.transform.TransformPoint(p1)
+}
+
+//Intervals returns the spans ray r spends inside the inner shape, converted back to distances along the world space ray
+func (in Instance) Intervals(r rays.Ray) []Interval {
+	//objectRay normalizes the direction, which scales the distances along it
+	scale := rays.Magnitude(in.inverse.TransformDirection(r.Direction))
+	res := IntervalsOf(in.Inner, in.objectRay(r))
+	for i := range res {
+		res[i].Enter /= scale
+		res[i].Exit /= scale
+	}
+	return res
+}
+
+//ColorAtPoint returns the inner shape's color at the matching object space point
+func (in Instance) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return in.Inner.ColorAtPoint(in.inverse.TransformPoint(p), in.inverse.TransformPoint(cameraPosition))
+}
+
+//ColorAtPointDifferential returns the inner shape's color at the matching object space point, with the ray differential moved into object space too
+func (in Instance) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	obj := rays.RayDifferential{
+		Ray:              in.objectRay(r.Ray),
+		RxOrigin:         in.inverse.TransformPoint(r.RxOrigin),
+		RxDirection:      in.inverse.TransformDirection(r.RxDirection),
+		RyOrigin:         in.inverse.TransformPoint(r.RyOrigin),
+		RyDirection:      in.inverse.TransformDirection(r.RyDirection),
+		HasDifferentials: r.HasDifferentials,
+	}
+	return ColorAt(in.Inner, in.inverse.TransformPoint(p), obj)
+}
+
+//NormalAtPoint returns the inner shape's shading normal, moved into world space by the inverse transpose so it stays perpendicular under non-uniform scaling
+func (in Instance) NormalAtPoint(p rays.Point) rays.Ray {
+	return in.worldNormal(p, in.Inner.NormalAtPoint(in.inverse.TransformPoint(p)))
+}
+
+//GeometricNormalAtPoint returns the inner shape's true surface normal in world space
+func (in Instance) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	return in.worldNormal(p, GeometricNormal(in.Inner, in.inverse.TransformPoint(p)))
+}
+
+//UVAtPoint forwards the call to the inner shape, if it provides a surface parameterization
+func (in Instance) UVAtPoint(p rays.Point) (u float32, v float32) {
+	if uv, ok := in.Inner.(UVMapper); ok {
+		return uv.UVAtPoint(in.inverse.TransformPoint(p))
+	}
+	return 0, 0
+}
+
+//Bounds returns the box enclosing the transformed inner shape
+func (in Instance) Bounds() BoundingBox {
+	return BoundsOf(in.Inner).Transform(in.transform)
+}
+
+//objectRay returns r in object space, with its direction normalized as the shapes expect
+func (in Instance) objectRay(r rays.Ray) rays.Ray {
+	obj := in.inverse.TransformRay(r)
+	obj.Direction = normalizeVector(obj.Direction)
+	return obj
+}
+
+func (in Instance) worldNormal(p rays.Point, objectNormal rays.Ray) rays.Ray {
+	n := in.inverse.Transpose().TransformDirection(objectNormal.Direction)
+	return rays.Ray{Origin: p, Direction: normalizeVector(n)}
+}
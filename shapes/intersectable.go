@@ -1,6 +1,8 @@
 package shapes
 
 import (
+	"math"
+
 	"github.com/flabbergasted/RayTracer/rays"
 )
 
@@ -11,3 +13,38 @@ type Intersectable interface {
 	NormalAtPoint(p rays.Point) rays.Ray
 	Equals(i Intersectable) bool
 }
+
+//hitEpsilon is the smallest ray distance the analytic shapes accept as a hit, so rays leaving a surface do not hit it again
+const hitEpsilon = 1e-3
+
+//nearestHits returns the nearest and furthest of the candidate ray distances ts that lie in front of the ray origin,
+//as the pair of points DoesRayIntersect reports.  NaN candidates (misses) are ignored.
+func nearestHits(r rays.Ray, ts ...float32) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	near, far := float32(math.Inf(1)), float32(math.Inf(-1))
+	for _, t := range ts {
+		if t > hitEpsilon {
+			near, far = minFloat(near, t), maxFloat(far, t)
+		}
+	}
+	if math.IsInf(float64(near), 1) {
+		return false, intersectPoint0, intersectPoint1
+	}
+	return true, rays.Add(r.Origin, rays.Multiply(r.Direction, near)), rays.Add(r.Origin, rays.Multiply(r.Direction, far))
+}
+
+//solveQuadratic returns the real roots of a*t^2 + b*t + c from rays.SolveQuadratic as float32s, smallest first.  A repeated root is returned
+//twice, a single root from a linear equation comes with NaN, and NaN is returned for both when there are no roots.
+func solveQuadratic(a float32, b float32, c float32) (float32, float32) {
+	roots := rays.SolveQuadratic(float64(a), float64(b), float64(c))
+	nan := float32(math.NaN())
+	switch {
+	case len(roots) == 0:
+		return nan, nan
+	case len(roots) == 2:
+		return float32(roots[0]), float32(roots[1])
+	case a == 0:
+		return float32(roots[0]), nan
+	default:
+		return float32(roots[0]), float32(roots[0])
+	}
+}
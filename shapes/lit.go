@@ -8,11 +8,14 @@ var ShadowObjects []Intersectable
 //ReflectiveObjects contains all shapes that could be reflected in another shape
 var ReflectiveObjects []Intersectable
 
+//ambientAdjust is the fraction of a shape's color it keeps where no light reaches it
+const ambientAdjust = 0.155
+
 //Lighting represents a shape lit by some method
 type Lighting struct {
 	Inner       Intersectable
 	LightSource rays.Point
-	lightMethod func(p rays.Point, cameraPosition rays.Point, l Lighting) rays.Point
+	lightMethod func(p rays.Point, r rays.RayDifferential, l Lighting) rays.Point
 }
 
 //Equals returns true if the 2 Intersectables are equivalent
@@ -32,7 +35,12 @@ func (l Lighting) DoesRayIntersect(r rays.Ray) (bool, rays.Point, rays.Point) {
 
 //ColorAtPoint forwards the call to the decorated shape
 func (l Lighting) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
-	return l.lightMethod(p, cameraPosition, l)
+	return l.lightMethod(p, rays.RayDifferential{Ray: rays.Ray{Origin: cameraPosition}}, l)
+}
+
+//ColorAtPointDifferential forwards the call to the decorated shape, keeping the ray's differentials for texture filtering
+func (l Lighting) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return l.lightMethod(p, r, l)
 }
 
 //NormalAtPoint returns the surface normal for this intersectable shape at point p
@@ -40,44 +48,42 @@ func (l Lighting) NormalAtPoint(p rays.Point) rays.Ray {
 	return l.Inner.NormalAtPoint(p)
 }
 
+//GeometricNormalAtPoint forwards the call to the decorated shape
+func (l Lighting) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	return GeometricNormal(l.Inner, p)
+}
+
+//Bounds forwards the call to the decorated shape
+func (l Lighting) Bounds() BoundingBox {
+	return BoundsOf(l.Inner)
+}
+
+//UVAtPoint forwards the call to the decorated shape, if it provides a surface parameterization
+func (l Lighting) UVAtPoint(p rays.Point) (u float32, v float32) {
+	if uv, ok := l.Inner.(UVMapper); ok {
+		return uv.UVAtPoint(p)
+	}
+	return 0, 0
+}
+
 //returns lighting based on the reflection angle a point has fromt he light source.
-func reflectionAngleLight(p rays.Point, cameraPosition rays.Point, l Lighting) rays.Point {
+func reflectionAngleLight(p rays.Point, r rays.RayDifferential, l Lighting) rays.Point {
 	var lightingAdjust, maxAngle float32 = 0, 1.57
-	color := l.Inner.ColorAtPoint(p, cameraPosition)
+	color := ColorAt(l.Inner, p, r)
 	pointNormal := l.Inner.NormalAtPoint(p)
 	pointToLight := rays.Ray{Direction: rays.Subtract(p, l.LightSource)}
 	angleDifference := rays.Angle(pointNormal, pointToLight)
-	minAdjust := float32(0.155)
+	minAdjust := float32(ambientAdjust)
 
 	lightingAdjust = 1 - (angleDifference / maxAngle)
 	if lightingAdjust < minAdjust {
 		lightingAdjust = minAdjust
 	}
-	if isInShadow(p, l) {
-		lightingAdjust = minAdjust
-	}
-	return rays.Multiply(color, lightingAdjust)
-}
 
-func isInShadow(p rays.Point, l Lighting) bool {
-	res := false
-
-	//create ray between this point and the light source
-	shadowRay := rays.Ray{Origin: p, Direction: rays.Normalize(p, l.LightSource)}
-	shadowMag := rays.Magnitude(rays.Subtract(p, l.LightSource))
-
-	//check shapes list for intersection, if one is found this shape is in shadow.
-	for _, e := range ShadowObjects {
-		if !e.Equals(l) {
-			if do, intersectPoint, _ := e.DoesRayIntersect(shadowRay); do {
-				intersectRay := rays.Subtract(p, intersectPoint)
-				if shadowMag > rays.Magnitude(intersectRay) {
-					return true
-				}
-			}
-		}
-	}
-	return res
+	//shadows cut the light down to the minimum, or partly for shapes that let light through
+	transmittance := lightTransmittance(p, l.LightSource, l)
+	adjust := rays.Add(rays.Point{X: minAdjust, Y: minAdjust, Z: minAdjust}, rays.Multiply(transmittance, lightingAdjust-minAdjust))
+	return multiplyColors(color, adjust)
 }
 
 /* //returns lighting based on how far a point is away from the light source.
@@ -115,7 +121,14 @@ func NewLitCircle(c Circle, lightSource rays.Point) Lighting {
 	return Lighting{Inner: c, LightSource: lightSource, lightMethod: ambientLight}
 } */
 
-//NewLightSourceCircle creates a sphere lit by a light source
+//NewLightSourceCircle creates a sphere lit by a light source.  A Volume is lit by the light its medium scatters instead, and a Subsurface
+//shape by the light scattered beneath its surface.
 func NewLightSourceCircle(c Intersectable, lightSource rays.Point) Lighting {
+	switch c.(type) {
+	case Volume:
+		return Lighting{Inner: c, LightSource: lightSource, lightMethod: volumeLight}
+	case Subsurface:
+		return Lighting{Inner: c, LightSource: lightSource, lightMethod: subsurfaceLight}
+	}
 	return Lighting{Inner: c, LightSource: lightSource, lightMethod: reflectionAngleLight}
 }
@@ -0,0 +1,105 @@
+package shapes
+
+import (
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/textures"
+)
+
+//Space selects which coordinate system a texture is evaluated in
+type Space int
+
+const (
+	//WorldSpace evaluates textures at the hit point as-is, so the pattern stays put when the shape moves
+	WorldSpace Space = iota
+	//ObjectSpace evaluates textures relative to the shape's own origin, so the pattern moves with the shape
+	ObjectSpace
+)
+
+//Material describes how the surface of a shape is colored.  The zero value leaves the shape's flat Color untouched.
+type Material struct {
+	Texture      textures.Texture
+	TextureSpace Space
+
+	NormalMap textures.Texture //tangent-space normal map, each color channel in [0,1] encodes a component in [-1,1]
+	BumpMap   textures.Texture //height map, read from the luminance of the texture
+	BumpScale float32          //world units of height for a bump map luminance of 1
+}
+
+//UVMapper is implemented by shapes that provide a 2d parameterization of their surface, with u and v in the range [0,1]
+type UVMapper interface {
+	UVAtPoint(p rays.Point) (u float32, v float32)
+}
+
+//VertexColorer is implemented by shapes that store colors at their vertices, which textures receive as the sample's VertexColor
+type VertexColorer interface {
+	VertexColorAtPoint(p rays.Point) (color rays.Point, ok bool)
+}
+
+//DifferentialColorer is implemented by shapes that can filter their textures over the footprint of the incoming ray
+type DifferentialColorer interface {
+	ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point
+}
+
+//ColorAt returns the color of shape i at point p as seen along r, using the ray's differentials if the shape supports them
+func ColorAt(i Intersectable, p rays.Point, r rays.RayDifferential) rays.Point {
+	if dc, ok := i.(DifferentialColorer); ok {
+		return dc.ColorAtPointDifferential(p, r)
+	}
+	return i.ColorAtPoint(p, r.Origin)
+}
+
+//surfaceColor returns the material's texture color at p, or base if no texture is attached.
+//origin is the shape's reference point, used when the texture is evaluated in object space.
+func (m Material) surfaceColor(base rays.Point, p rays.Point, origin rays.Point, uv UVMapper) rays.Point {
+	return m.filteredColor(base, p, origin, uv, rays.Point{}, rays.RayDifferential{})
+}
+
+//filteredColor is surfaceColor with the texture footprint estimated from where r's differentials cross the surface's tangent plane at p
+func (m Material) filteredColor(base rays.Point, p rays.Point, origin rays.Point, uv UVMapper, n rays.Point, r rays.RayDifferential) rays.Point {
+	if m.Texture == nil {
+		return base
+	}
+
+	s := m.sample(p, origin, uv)
+	if px, py, ok := r.Transfer(p, n); ok {
+		ux, vx := uv.UVAtPoint(px)
+		uy, vy := uv.UVAtPoint(py)
+		s.DUDX, s.DVDX = wrapDelta(ux-s.U), wrapDelta(vx-s.V)
+		s.DUDY, s.DVDY = wrapDelta(uy-s.U), wrapDelta(vy-s.V)
+	}
+	return m.Texture.ColorAt(s)
+}
+
+//sample returns the texture sample for point p in the material's texture space
+func (m Material) sample(p rays.Point, origin rays.Point, uv UVMapper) textures.Sample {
+	s := textures.Sample{P: p}
+	s.U, s.V = uv.UVAtPoint(p)
+	if vc, ok := uv.(VertexColorer); ok {
+		s.VertexColor, _ = vc.VertexColorAtPoint(p)
+	}
+	if m.TextureSpace == ObjectSpace {
+		s.P = rays.Subtract(p, origin)
+	}
+	return s
+}
+
+//wrapDelta returns the shortest difference between two UV coordinates, so a footprint straddling a seam (such as u=0/u=1 on a sphere) stays small
+func wrapDelta(d float32) float32 {
+	if d > 0.5 {
+		return d - 1
+	}
+	if d < -0.5 {
+		return d + 1
+	}
+	return d
+}
+
+//reflectDifferential reflects r off shape i at p, using the normal where each auxiliary ray lands so curved surfaces spread the footprint
+func reflectDifferential(i Intersectable, p rays.Point, surfaceNormal rays.Ray, r rays.RayDifferential) rays.RayDifferential {
+	px, py, ok := r.Transfer(p, surfaceNormal.Direction)
+	if !ok {
+		r.HasDifferentials = false
+		return r.Reflect(surfaceNormal, px, px, py, py)
+	}
+	return r.Reflect(surfaceNormal, px, i.NormalAtPoint(px).Direction, py, i.NormalAtPoint(py).Direction)
+}
@@ -0,0 +1,195 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/textures"
+)
+
+//scatterSteps is how many points along a ray are lit when gathering the light a medium scatters towards the viewer
+const scatterSteps = 48
+
+//lightIntensity scales the light scattered by media.  Lights have no falloff, like the surface lighting, and are scaled so that an evenly
+//scattering medium that scatters all the light reaching it, thick enough to hide what is behind it, looks as bright as a white surface facing the light.
+const lightIntensity = 4 * math.Pi
+
+//Medium describes a participating medium such as fog, smoke or murky water, which absorbs and scatters the light travelling through it
+type Medium interface {
+	//Coefficients returns the fraction of light the medium absorbs and scatters per unit of distance at p, per color channel
+	Coefficients(p rays.Point) (absorption rays.Point, scattering rays.Point)
+	//Transmittance returns the fraction of light, per color channel, that passes through the medium along r from distance t0 to t1.
+	//r's direction must be normalized.
+	Transmittance(r rays.Ray, t0 float32, t1 float32) rays.Point
+	//Phase returns how much of the light scattered at a point goes off at an angle with cosine cosTheta from the direction it was travelling,
+	//as a density over the sphere of directions
+	Phase(cosTheta float32) float32
+}
+
+//HomogeneousMedium is a medium with the same density everywhere, such as an even fog or tinted glass filled with smoke.
+//Light passing through a distance d keeps exp(-(Absorption+Scattering)*d) of its strength.
+type HomogeneousMedium struct {
+	Absorption rays.Point //light absorbed per unit of distance, per color channel
+	Scattering rays.Point //light scattered per unit of distance, per color channel
+	//G is the Henyey-Greenstein asymmetry of the scattering, from -1 (light bounces back the way it came) through 0 (light scatters evenly
+	//in every direction) to 1 (light carries on the way it was going).  Haze and fog scatter forwards, around 0.7.
+	G float32
+}
+
+//Coefficients returns the medium's absorption and scattering, which are the same everywhere
+func (m HomogeneousMedium) Coefficients(p rays.Point) (absorption rays.Point, scattering rays.Point) {
+	return m.Absorption, m.Scattering
+}
+
+//Transmittance returns the fraction of light left after travelling from t0 to t1 through the medium
+func (m HomogeneousMedium) Transmittance(r rays.Ray, t0 float32, t1 float32) rays.Point {
+	return expColor(rays.Multiply(rays.Add(m.Absorption, m.Scattering), -(t1 - t0)))
+}
+
+//Phase returns the Henyey-Greenstein phase function for the medium's asymmetry
+func (m HomogeneousMedium) Phase(cosTheta float32) float32 {
+	return HenyeyGreenstein(cosTheta, m.G)
+}
+
+//HenyeyGreenstein returns the Henyey-Greenstein phase function, which spreads scattered light over the sphere of directions with asymmetry g
+//from -1 to 1.  cosTheta is the cosine of the angle between the direction the light was travelling and the direction it scatters in.
+//The result integrates to 1 over the sphere.
+func HenyeyGreenstein(cosTheta float32, g float32) float32 {
+	denominator := 1 + g*g - 2*g*cosTheta
+	return (1 - g*g) / (4 * math.Pi * denominator * float32(math.Sqrt(float64(denominator))))
+}
+
+//Transmitter is implemented by shapes that let some light through instead of blocking it, such as a Volume.  Shadows cast by them are
+//dimmed rather than solid.
+type Transmitter interface {
+	//Transmittance returns the fraction of light, per color channel, that makes it through the shape along r between its origin and distance.
+	//r's direction must be normalized.
+	Transmittance(r rays.Ray, distance float32) rays.Point
+}
+
+//Atmosphere fills the space between shapes with a medium, such as fog or haze.  Anything seen through it is dimmed, and light from its Lights
+//scattered towards the viewer brightens it, showing the beams of light shapes leave between their shadows.
+type Atmosphere struct {
+	Medium Medium       //nil for a vacuum
+	Lights []rays.Point //positions of the lights scattering through the medium
+	//Distance is how far rays that hit nothing travel through the medium, zero leaves the background clear
+	Distance float32
+}
+
+//Fog is the atmosphere filling the scene, applied to every camera ray and shadow.  The zero value is a vacuum.
+var Fog Atmosphere
+
+//Apply returns color, seen at distance along r, as it arrives at r's origin through the atmosphere: dimmed by the medium and brightened by
+//the light it scatters along the way.  Pass an infinite distance for rays that hit nothing.
+func (a Atmosphere) Apply(r rays.Ray, distance float32, color rays.Point) rays.Point {
+	if a.Medium == nil {
+		return color
+	}
+	if math.IsInf(float64(distance), 1) {
+		if a.Distance == 0 {
+			return color
+		}
+		distance = a.Distance
+	}
+
+	r.Direction = normalizeVector(r.Direction)
+	scattered, transmittance := singleScatter(a.Medium, r, 0, distance, a.Lights)
+	return rays.Add(scattered, multiplyColors(color, transmittance))
+}
+
+//singleScatter gathers the light from lights scattered once towards r's origin by medium m between distances t0 and t1 along r, following the
+//light back to each light through ShadowObjects so shapes cast shadows into the medium.  Also returns the transmittance from t0 to t1.
+//r's direction must be normalized.
+func singleScatter(m Medium, r rays.Ray, t0 float32, t1 float32, lights []rays.Point) (scattered rays.Point, transmittance rays.Point) {
+	transmittance = rays.Point{X: 1, Y: 1, Z: 1}
+	if t1 <= t0 {
+		return scattered, transmittance
+	}
+	if ds, ok := m.(DistanceSampler); ok {
+		return sampledScatter(ds, m, r, t0, t1, lights)
+	}
+
+	//light from beyond where the medium lets almost nothing through cannot be seen, so the samples are spent before that
+	end := t1
+	for i := 0; i < 16 && textures.Luminance(m.Transmittance(r, t0, t0+(end-t0)/2)) < 1e-3; i++ {
+		end = t0 + (end-t0)/2
+	}
+
+	//stratified samples, offset per ray so the steps show as fine noise instead of bands
+	step := (end - t0) / scatterSteps
+	t, previous := t0+step*rayJitter(r), t0
+	for i := 0; i < scatterSteps; i++ {
+		transmittance = multiplyColors(transmittance, m.Transmittance(r, previous, t))
+		p := rays.Add(r.Origin, rays.Multiply(r.Direction, t))
+		_, scattering := m.Coefficients(p)
+		if scattering != (rays.Point{}) {
+			for _, light := range lights {
+				toLight := normalizeVector(rays.Subtract(light, p))
+				strength := m.Phase(rays.DotProduct(toLight, r.Direction)) * lightIntensity * step
+				arriving := multiplyColors(lightTransmittance(p, light, nil), scattering)
+				scattered = rays.Add(scattered, rays.Multiply(multiplyColors(arriving, transmittance), strength))
+			}
+		}
+		previous, t = t, t+step
+	}
+	return scattered, multiplyColors(transmittance, m.Transmittance(r, previous, t1))
+}
+
+//lightTransmittance returns the fraction of the light from light that reaches p, per color channel.  Solid ShadowObjects between them block
+//it, Transmitters and the Fog dim it.  self is left out, so surfaces do not shadow themselves; pass nil to include every shape.
+func lightTransmittance(p rays.Point, light rays.Point, self Intersectable) rays.Point {
+	transmittance := rays.Point{X: 1, Y: 1, Z: 1}
+	distance := rays.Magnitude(rays.Subtract(light, p))
+	shadowRay := rays.Ray{Origin: p, Direction: rays.Normalize(p, light)}
+	if self != nil {
+		shadowRay.Origin = shadowRayOrigin(self, p, light)
+	}
+
+	for _, e := range ShadowObjects {
+		if self != nil && e.Equals(self) {
+			continue
+		}
+		if t, ok := transmitterOf(e); ok {
+			transmittance = multiplyColors(transmittance, t.Transmittance(shadowRay, distance))
+			continue
+		}
+		if do, intersectPoint, _ := e.DoesRayIntersect(shadowRay); do {
+			if distance > rays.Magnitude(rays.Subtract(p, intersectPoint)) {
+				return rays.Point{}
+			}
+		}
+	}
+	if Fog.Medium != nil {
+		transmittance = multiplyColors(transmittance, Fog.Medium.Transmittance(shadowRay, 0, distance))
+	}
+	return transmittance
+}
+
+//transmitterOf returns i as a Transmitter, looking through any lighting wrapped around it
+func transmitterOf(i Intersectable) (Transmitter, bool) {
+	if l, ok := i.(Lighting); ok {
+		i = l.Inner
+	}
+	t, ok := i.(Transmitter)
+	return t, ok
+}
+
+//rayJitter returns a number in [0,1) that is fixed for a ray but varies between neighbouring rays
+func rayJitter(r rays.Ray) float32 {
+	h := math.Float32bits(r.Direction.X)*73856093 ^ math.Float32bits(r.Direction.Y)*19349663 ^ math.Float32bits(r.Direction.Z)*83492791
+	h ^= math.Float32bits(r.Origin.X) ^ math.Float32bits(r.Origin.Y)*31 ^ math.Float32bits(r.Origin.Z)*17
+	h ^= h >> 13
+	h *= 0x5bd1e995
+	h ^= h >> 15
+	return float32(h&0xffffff) / (1 << 24)
+}
+
+//multiplyColors multiplies two colors channel by channel
+func multiplyColors(a rays.Point, b rays.Point) rays.Point {
+	return rays.Point{X: a.X * b.X, Y: a.Y * b.Y, Z: a.Z * b.Z}
+}
+
+//expColor returns e raised to each channel of c
+func expColor(c rays.Point) rays.Point {
+	return rays.Point{X: float32(math.Exp(float64(c.X))), Y: float32(math.Exp(float64(c.Y))), Z: float32(math.Exp(float64(c.Z)))}
+}
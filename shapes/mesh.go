@@ -0,0 +1,339 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/textures"
+)
+
+//Mesh represents a triangle mesh.  Faces index into Vertices with counter-clockwise winding when viewed from outside.
+//UVs, Normals and Colors are optional and, if present, hold one entry per vertex.  Normals point inward like the face normals and are
+//interpolated across each triangle for smooth shading, Colors are passed to textures as the sample's VertexColor (see textures.VertexColor).
+//Metallic and Roughness make the mesh reflect the ReflectiveObjects around it like metal, tinted by its color.  Metallic runs from 0 (not
+//metal) to 1 and Roughness from 0 (a sharp mirror) to 1, blurring the reflection.  With no specular highlights to carry the look of rough
+//metal, reflections fade out as Roughness rises, leaving the surface color: a mesh reflects Metallic*(1-Roughness) of its surroundings.
+//Vertex data lives in slices, so copies of a Mesh share it.
+type Mesh struct {
+	Vertices  []rays.Point
+	Faces     [][3]int
+	UVs       [][2]float32
+	Normals   []rays.Point
+	Colors    []rays.Point
+	Color     rays.Point
+	Metallic  float32
+	Roughness float32
+	//MetallicRoughnessMap scales Metallic by its blue channel and Roughness by its green channel, the layout glTF uses
+	MetallicRoughnessMap textures.Texture
+	Material
+	faceNormals []rays.Point
+	bounds      BoundingBox
+	tree        bvh
+}
+
+//meshEpsilon is the smallest ray distance accepted as a hit, so rays leaving a triangle do not hit it again
+const meshEpsilon = 1e-4
+
+//glossySamples is how many rays are traced to blur the reflections of rough metal
+const glossySamples = 8
+
+//NewMesh creates a new mesh from the provided vertices and faces.  Precalculates face normals and a hierarchy over the faces for performance.
+func NewMesh(vertices []rays.Point, faces [][3]int, color rays.Point) Mesh {
+	m := Mesh{Vertices: vertices, Faces: faces, Color: color}
+	m.bounds = emptyBounds()
+	for _, v := range vertices {
+		m.bounds = m.bounds.Extend(v)
+	}
+	m.faceNormals = make([]rays.Point, len(faces))
+	boxes := make([]BoundingBox, len(faces))
+	for i, f := range faces {
+		e1 := rays.Subtract(vertices[f[1]], vertices[f[0]])
+		e2 := rays.Subtract(vertices[f[2]], vertices[f[0]])
+		m.faceNormals[i] = normalizeVector(cross(e2, e1)) //inward facing, like the other shapes
+		//padded so points found on a face still fall inside its box despite rounding
+		boxes[i] = BoundingBox{Min: vertices[f[0]], Max: vertices[f[0]]}.Extend(vertices[f[1]]).Extend(vertices[f[2]]).Pad(meshEpsilon * 10)
+	}
+	m.tree = newBVH(boxes)
+	return m
+}
+
+//Equals returns true if the 2 Intersectables are equivalent.  Meshes are equal when they share the same vertex data.
+func (m Mesh) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Mesh:
+		compare := i.(Mesh)
+		if len(m.Vertices) == 0 || len(compare.Vertices) == 0 {
+			return len(m.Vertices) == len(compare.Vertices)
+		}
+		return &m.Vertices[0] == &compare.Vertices[0] && len(m.Faces) == len(compare.Faces)
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect tests the triangles near the ray with the Moller-Trumbore algorithm: https://www.scratchapixel.com/lessons/3d-basic-rendering/ray-tracing-rendering-a-triangle/moller-trumbore-ray-triangle-intersection
+//intersectPoint0 is the nearest hit and intersectPoint1 the furthest.
+func (m Mesh) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	near, far := float32(math.MaxFloat32), float32(-1)
+	m.tree.rayItems(r, func(i int) {
+		if t, _, _, hit := m.intersectFace(i, r); hit {
+			if t < near {
+				near = t
+			}
+			if t > far {
+				far = t
+			}
+		}
+	})
+
+	if far < 0 {
+		return false, intersectPoint0, intersectPoint1
+	}
+	return true, rays.Add(r.Origin, rays.Multiply(r.Direction, near)), rays.Add(r.Origin, rays.Multiply(r.Direction, far))
+}
+
+//ColorAtPoint returns the color at a given point.
+func (m Mesh) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return m.ColorAtPointDifferential(p, rays.RayDifferential{Ray: rays.Ray{Origin: cameraPosition}})
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint and blending in
+//reflections for metallic materials
+func (m Mesh) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	n := m.NormalAtPoint(p)
+	return m.metalColor(m.filteredColor(m.Color, p, rays.Point{}, m, n.Direction, r), p, n, r)
+}
+
+//metalColor returns color at p, seen along r, with the mesh's metallic reflection of the ReflectiveObjects around it blended in.  n is the
+//shading normal at p.
+func (m Mesh) metalColor(color rays.Point, p rays.Point, n rays.Ray, r rays.RayDifferential) rays.Point {
+	metallic, roughness := m.Metallic, m.Roughness
+	if m.MetallicRoughnessMap != nil {
+		factors := m.MetallicRoughnessMap.ColorAt(m.sample(p, rays.Point{}, m))
+		metallic, roughness = metallic*factors.Z, roughness*factors.Y
+	}
+	metallic, roughness = maxFloat(clampUnit(metallic), 0), maxFloat(clampUnit(roughness), 0)
+	reflectivity := metallic * (1 - roughness)
+	if reflectivity == 0 {
+		return color
+	}
+
+	r.Ray = rays.Ray{Direction: rays.Normalize(r.Origin, p), Origin: r.Origin}
+	reflectRay := reflectDifferential(m, p, n, r)
+	mirror := normalizeVector(reflectRay.Ray.Direction)
+	samples := 1
+	if roughness > 0 {
+		samples = glossySamples
+	}
+
+	var reflected rays.Point
+	for s := 0; s < samples; s++ {
+		ray := reflectRay
+		if roughness > 0 {
+			//spread the rays over a disk around the mirror direction in a golden angle spiral, keeping them on the side of the surface the
+			//mirror ray leaves from
+			helper := rays.Point{X: 1}
+			if absFloat(mirror.X) > 0.9 {
+				helper = rays.Point{Y: 1}
+			}
+			tangent := normalizeVector(cross(helper, mirror))
+			bitangent := cross(mirror, tangent)
+			radius := roughness * roughness * float32(math.Sqrt((float64(s)+0.5)/glossySamples))
+			angle := float64(s) * math.Pi * (3 - math.Sqrt(5))
+			offset := rays.Add(rays.Multiply(tangent, radius*float32(math.Cos(angle))), rays.Multiply(bitangent, radius*float32(math.Sin(angle))))
+			direction := normalizeVector(rays.Add(mirror, offset))
+			if (rays.DotProduct(direction, n.Direction) > 0) != (rays.DotProduct(mirror, n.Direction) > 0) {
+				direction = mirror
+			}
+			ray.Ray.Direction = direction
+			ray.HasDifferentials = false
+		}
+		reflected = rays.Add(reflected, m.traceReflection(p, ray))
+	}
+	reflected = rays.Multiply(reflected, 1/float32(samples))
+
+	//metals tint what they reflect with their own color
+	return rays.Add(rays.Multiply(color, 1-reflectivity), rays.Multiply(multiplyColors(reflected, color), reflectivity))
+}
+
+//traceReflection returns the color of the nearest of the ReflectiveObjects, other than the mesh itself, seen from p along r, dimmed by the
+//Fog on the way
+func (m Mesh) traceReflection(p rays.Point, r rays.RayDifferential) rays.Point {
+	nearest := float32(math.Inf(1))
+	var hitObject Intersectable
+	var hitPoint rays.Point
+	for _, e := range ReflectiveObjects {
+		if e.Equals(m) {
+			continue
+		}
+		if do, intersectPoint, _ := e.DoesRayIntersect(r.Ray); do {
+			if d := rays.Magnitude(rays.Subtract(intersectPoint, p)); d < nearest {
+				nearest, hitObject, hitPoint = d, e, intersectPoint
+			}
+		}
+	}
+	if hitObject == nil {
+		return Fog.Apply(r.Ray, nearest, rays.Point{})
+	}
+	return Fog.Apply(r.Ray, nearest, ColorAt(hitObject, hitPoint, r))
+}
+
+//NormalAtPoint returns the surface normal of the triangle containing point p, interpolated from the vertex normals if the mesh has them
+//and perturbed by any normal or bump map
+func (m Mesh) NormalAtPoint(p rays.Point) rays.Ray {
+	if len(m.Normals) == 0 {
+		return m.shadingNormal(m.GeometricNormalAtPoint(p), rays.Point{}, m)
+	}
+	face, b0, b1, b2 := m.faceAtPoint(p)
+	if face < 0 {
+		return rays.Ray{Origin: p}
+	}
+	f := m.Faces[face]
+	n := rays.Add(rays.Add(rays.Multiply(m.Normals[f[0]], b0), rays.Multiply(m.Normals[f[1]], b1)), rays.Multiply(m.Normals[f[2]], b2))
+	return m.shadingNormal(rays.Ray{Origin: p, Direction: normalizeVector(n)}, rays.Point{}, m)
+}
+
+//GeometricNormalAtPoint returns the flat normal of the triangle containing point p
+func (m Mesh) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	face, _, _, _ := m.faceAtPoint(p)
+	if face < 0 {
+		return rays.Ray{Origin: p}
+	}
+	return rays.Ray{Origin: p, Direction: m.faceNormals[face]}
+}
+
+//tangentsAtPoint returns the directions the triangle containing p moves in as u and v increase, or its first two edges if the mesh has no UVs
+func (m Mesh) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	face, _, _, _ := m.faceAtPoint(p)
+	if face < 0 {
+		return dpdu, dpdv
+	}
+	f := m.Faces[face]
+	e1 := rays.Subtract(m.Vertices[f[1]], m.Vertices[f[0]])
+	e2 := rays.Subtract(m.Vertices[f[2]], m.Vertices[f[0]])
+	if len(m.UVs) == 0 {
+		return e1, e2
+	}
+
+	du1, dv1 := m.UVs[f[1]][0]-m.UVs[f[0]][0], m.UVs[f[1]][1]-m.UVs[f[0]][1]
+	du2, dv2 := m.UVs[f[2]][0]-m.UVs[f[0]][0], m.UVs[f[2]][1]-m.UVs[f[0]][1]
+	det := du1*dv2 - du2*dv1
+	if det == 0 {
+		return e1, e2
+	}
+	dpdu = rays.Divide(rays.Subtract(rays.Multiply(e1, dv2), rays.Multiply(e2, dv1)), det)
+	dpdv = rays.Divide(rays.Subtract(rays.Multiply(e2, du1), rays.Multiply(e1, du2)), det)
+	return dpdu, dpdv
+}
+
+//UVAtPoint interpolates the per-vertex UVs of the triangle containing point p
+func (m Mesh) UVAtPoint(p rays.Point) (u float32, v float32) {
+	face, b0, b1, b2 := m.faceAtPoint(p)
+	if face < 0 || len(m.UVs) == 0 {
+		return 0, 0
+	}
+	f := m.Faces[face]
+	uv0, uv1, uv2 := m.UVs[f[0]], m.UVs[f[1]], m.UVs[f[2]]
+	u = b0*uv0[0] + b1*uv1[0] + b2*uv2[0]
+	v = b0*uv0[1] + b1*uv1[1] + b2*uv2[1]
+	return u, v
+}
+
+//VertexColorAtPoint interpolates the per-vertex colors of the triangle containing point p.  ok is false if the mesh has no vertex colors.
+func (m Mesh) VertexColorAtPoint(p rays.Point) (color rays.Point, ok bool) {
+	face, b0, b1, b2 := m.faceAtPoint(p)
+	if face < 0 || len(m.Colors) == 0 {
+		return color, false
+	}
+	f := m.Faces[face]
+	return rays.Add(rays.Add(rays.Multiply(m.Colors[f[0]], b0), rays.Multiply(m.Colors[f[1]], b1)), rays.Multiply(m.Colors[f[2]], b2)), true
+}
+
+//Bounds returns the box enclosing every vertex
+func (m Mesh) Bounds() BoundingBox {
+	return m.bounds
+}
+
+//intersectFace returns the ray distance and barycentric coordinates of the hit between r and face i
+func (m Mesh) intersectFace(i int, r rays.Ray) (t float32, b1 float32, b2 float32, hit bool) {
+	f := m.Faces[i]
+	return intersectTriangle(r, m.Vertices[f[0]], m.Vertices[f[1]], m.Vertices[f[2]])
+}
+
+//intersectTriangle intersects r with the triangle v0 v1 v2 by the Moller-Trumbore algorithm, returning the ray distance and the barycentric weights of v1 and v2
+func intersectTriangle(r rays.Ray, v0 rays.Point, v1 rays.Point, v2 rays.Point) (t float32, b1 float32, b2 float32, hit bool) {
+	e1 := rays.Subtract(v1, v0)
+	e2 := rays.Subtract(v2, v0)
+
+	pvec := cross(r.Direction, e2)
+	det := rays.DotProduct(e1, pvec)
+	if det > -1e-8 && det < 1e-8 {
+		return 0, 0, 0, false
+	}
+	invDet := 1 / det
+
+	tvec := rays.Subtract(r.Origin, v0)
+	b1 = rays.DotProduct(tvec, pvec) * invDet
+	if b1 < 0 || b1 > 1 {
+		return 0, 0, 0, false
+	}
+	qvec := cross(tvec, e1)
+	b2 = rays.DotProduct(r.Direction, qvec) * invDet
+	if b2 < 0 || b1+b2 > 1 {
+		return 0, 0, 0, false
+	}
+
+	t = rays.DotProduct(e2, qvec) * invDet
+	return t, b1, b2, t > meshEpsilon
+}
+
+//faceAtPoint finds the triangle that point p lies on, returning its index (-1 if none) and the barycentric coordinates of p
+func (m Mesh) faceAtPoint(p rays.Point) (face int, b0 float32, b1 float32, b2 float32) {
+	best := float32(math.MaxFloat32)
+	face = -1
+	m.tree.pointItems(p, func(i int) {
+		f := m.Faces[i]
+		v0 := m.Vertices[f[0]]
+		dist := float32(math.Abs(float64(rays.DotProduct(rays.Subtract(p, v0), m.faceNormals[i]))))
+		if dist >= best {
+			return
+		}
+		c0, c1, c2, inside := barycentric(p, v0, m.Vertices[f[1]], m.Vertices[f[2]])
+		if inside {
+			best, face, b0, b1, b2 = dist, i, c0, c1, c2
+		}
+	})
+	return face, b0, b1, b2
+}
+
+//barycentric returns the barycentric coordinates of p projected onto triangle a,b,c and whether it falls inside (with a small tolerance)
+func barycentric(p rays.Point, a rays.Point, b rays.Point, c rays.Point) (float32, float32, float32, bool) {
+	const tolerance = 1e-4
+	v0, v1, v2 := rays.Subtract(b, a), rays.Subtract(c, a), rays.Subtract(p, a)
+	d00, d01, d11 := rays.DotProduct(v0, v0), rays.DotProduct(v0, v1), rays.DotProduct(v1, v1)
+	d20, d21 := rays.DotProduct(v2, v0), rays.DotProduct(v2, v1)
+	denom := d00*d11 - d01*d01
+	if denom == 0 {
+		return 0, 0, 0, false
+	}
+	w1 := (d11*d20 - d01*d21) / denom
+	w2 := (d00*d21 - d01*d20) / denom
+	w0 := 1 - w1 - w2
+	return w0, w1, w2, w0 >= -tolerance && w1 >= -tolerance && w2 >= -tolerance
+}
+
+//cross returns the cross product of two direction vectors, without normalizing
+func cross(a rays.Point, b rays.Point) rays.Point {
+	return rays.Point{
+		X: a.Y*b.Z - a.Z*b.Y,
+		Y: a.Z*b.X - a.X*b.Z,
+		Z: a.X*b.Y - a.Y*b.X}
+}
+
+//normalizeVector returns v scaled to length 1, or the zero vector if v has no length
+func normalizeVector(v rays.Point) rays.Point {
+	if v.X == 0 && v.Y == 0 && v.Z == 0 {
+		return v
+	}
+	return rays.Normalize(rays.Point{}, v)
+}
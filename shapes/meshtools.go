@@ -0,0 +1,299 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//MeshOptions selects the processing applied to a mesh before it is rendered, in the order welding, subdivision, displacement then normal smoothing.
+//The zero value leaves the mesh untouched.
+type MeshOptions struct {
+	Weld          bool
+	WeldTolerance float32       //vertices closer than this are merged when welding, zero only merges identical positions
+	Subdivisions  int           //levels of Loop subdivision, each splits every triangle into four
+	Displacement  *Displacement //displacement to apply, nil for none
+	CreaseAngle   float32       //in radians, faces meeting at a sharper angle keep a hard edge when smoothing normals.  Zero leaves the normals untouched.
+}
+
+//Process returns a new mesh with the options applied, or m itself if there is nothing to do
+func (o MeshOptions) Process(m Mesh) Mesh {
+	if o.Weld {
+		m = m.Weld(o.WeldTolerance)
+	}
+	if o.Subdivisions > 0 {
+		m = m.LoopSubdivide(o.Subdivisions)
+	}
+	if o.Displacement != nil {
+		m = m.Displace(*o.Displacement)
+	}
+	if o.CreaseAngle > 0 {
+		m = m.SmoothNormals(o.CreaseAngle)
+	}
+	return m
+}
+
+//Weld returns a new mesh with vertices closer than tolerance merged, so triangles that only touched now share their corners.
+//Vertices are only merged if their UVs, normals and colors (where present) also match, so texture seams are kept.
+//Triangles that collapse to a line or point are dropped.
+func (m Mesh) Weld(tolerance float32) Mesh {
+	cell := maxFloat(tolerance, 1e-6)
+	cellOf := func(p rays.Point) [3]int {
+		return [3]int{int(math.Floor(float64(p.X / cell))), int(math.Floor(float64(p.Y / cell))), int(math.Floor(float64(p.Z / cell)))}
+	}
+	same := func(a int, b int) bool {
+		if rays.Magnitude(rays.Subtract(m.Vertices[a], m.Vertices[b])) > tolerance {
+			return false
+		}
+		return (m.UVs == nil || m.UVs[a] == m.UVs[b]) && (m.Normals == nil || m.Normals[a] == m.Normals[b]) && (m.Colors == nil || m.Colors[a] == m.Colors[b])
+	}
+
+	//grid holds the welded vertices (as their original index) in each cell, so only neighbouring cells need searching
+	grid := map[[3]int][]int{}
+	remap := make([]int, len(m.Vertices))
+	var kept []int
+	for i, v := range m.Vertices {
+		c := cellOf(v)
+		remap[i] = -1
+		for dx := -1; dx <= 1 && remap[i] < 0; dx++ {
+			for dy := -1; dy <= 1 && remap[i] < 0; dy++ {
+				for dz := -1; dz <= 1 && remap[i] < 0; dz++ {
+					for _, k := range grid[[3]int{c[0] + dx, c[1] + dy, c[2] + dz}] {
+						if same(i, k) {
+							remap[i] = remap[k]
+							break
+						}
+					}
+				}
+			}
+		}
+		if remap[i] < 0 {
+			remap[i] = len(kept)
+			kept = append(kept, i)
+			grid[c] = append(grid[c], i)
+		}
+	}
+
+	w := newMeshBuilder(m, true)
+	for _, i := range kept {
+		w.addVertex(i)
+	}
+	for _, f := range m.Faces {
+		a, b, c := remap[f[0]], remap[f[1]], remap[f[2]]
+		if a != b && b != c && a != c {
+			w.faces = append(w.faces, [3]int{a, b, c})
+		}
+	}
+	return w.build()
+}
+
+//SmoothNormals returns a new mesh with per-vertex normals averaged from the faces around each vertex, weighted by their area.
+//Faces only contribute to each other's normals where they meet at less than creaseAngle (in radians), so vertices on sharper edges are split
+//and the edge stays hard.  The mesh should be welded first, since only faces sharing a vertex are smoothed together.
+func (m Mesh) SmoothNormals(creaseAngle float32) Mesh {
+	//area weighted face normals, and the faces around each vertex
+	areaNormals := make([]rays.Point, len(m.Faces))
+	vertexFaces := make([][]int, len(m.Vertices))
+	for i, f := range m.Faces {
+		e1 := rays.Subtract(m.Vertices[f[1]], m.Vertices[f[0]])
+		e2 := rays.Subtract(m.Vertices[f[2]], m.Vertices[f[0]])
+		areaNormals[i] = cross(e2, e1)
+		for _, v := range f {
+			vertexFaces[v] = append(vertexFaces[v], i)
+		}
+	}
+	minCos := float32(math.Cos(float64(creaseAngle)))
+
+	b := newMeshBuilder(m, false)
+	b.normals = []rays.Point{}
+	faces := make([][3]int, len(m.Faces))
+	for v, around := range vertexFaces {
+		//corners of faces around v whose smoothed normals match share a new vertex
+		var shared []int
+		for _, face := range around {
+			var n rays.Point
+			for _, other := range around {
+				if rays.DotProduct(m.faceNormals[face], m.faceNormals[other]) >= minCos {
+					n = rays.Add(n, areaNormals[other])
+				}
+			}
+			n = normalizeVector(n)
+
+			index := -1
+			for _, s := range shared {
+				if rays.DotProduct(b.normals[s], n) > 0.9999 {
+					index = s
+					break
+				}
+			}
+			if index < 0 {
+				index = b.addVertex(v)
+				b.normals = append(b.normals, n)
+				shared = append(shared, index)
+			}
+			for corner := range m.Faces[face] {
+				if m.Faces[face][corner] == v {
+					faces[face][corner] = index
+				}
+			}
+		}
+	}
+	b.faces = faces
+	return b.build()
+}
+
+//LoopSubdivide returns a new mesh refined by levels of Loop subdivision: https://www.microsoft.com/en-us/research/publication/smooth-subdivision-surfaces-based-on-triangles/
+//Each level splits every triangle into four and moves the vertices towards a smooth limit surface.  Boundary edges (and edges shared by more than
+//two faces) use the boundary rules, so open meshes keep their outline smooth rather than shrinking.  UVs and colors are interpolated linearly,
+//and any vertex normals are dropped since they no longer match the surface, so smooth normals should be generated afterwards.
+func (m Mesh) LoopSubdivide(levels int) Mesh {
+	for i := 0; i < levels; i++ {
+		m = m.loopSubdivideOnce()
+	}
+	return m
+}
+
+//loopEdge is an edge of the mesh being subdivided, with the vertex opposite it in each face it borders
+type loopEdge struct {
+	opposite []int
+	vertex   int //index of the new vertex on the edge
+}
+
+func (m Mesh) loopSubdivideOnce() Mesh {
+	//edges in the order they are first met, so the new mesh is the same every time
+	edges := map[[2]int]*loopEdge{}
+	var order [][2]int
+	edgeKey := func(a int, b int) [2]int {
+		if a > b {
+			a, b = b, a
+		}
+		return [2]int{a, b}
+	}
+	neighbours := make([][]int, len(m.Vertices))
+	for _, f := range m.Faces {
+		for c := 0; c < 3; c++ {
+			a, b, o := f[c], f[(c+1)%3], f[(c+2)%3]
+			key := edgeKey(a, b)
+			e, ok := edges[key]
+			if !ok {
+				e = &loopEdge{}
+				edges[key] = e
+				order = append(order, key)
+				neighbours[a] = append(neighbours[a], b)
+				neighbours[b] = append(neighbours[b], a)
+			}
+			e.opposite = append(e.opposite, o)
+		}
+	}
+
+	//boundary neighbours of each vertex, along edges that do not have exactly two faces
+	boundary := make([][]int, len(m.Vertices))
+	for _, key := range order {
+		if len(edges[key].opposite) != 2 {
+			boundary[key[0]] = append(boundary[key[0]], key[1])
+			boundary[key[1]] = append(boundary[key[1]], key[0])
+		}
+	}
+
+	b := newMeshBuilder(m, false)
+	for v := range m.Vertices {
+		b.addVertex(v)
+		var p rays.Point
+		switch {
+		case len(boundary[v]) == 2:
+			p = rays.Add(rays.Multiply(m.Vertices[v], 0.75), rays.Multiply(rays.Add(m.Vertices[boundary[v][0]], m.Vertices[boundary[v][1]]), 0.125))
+		case len(boundary[v]) > 0 || len(neighbours[v]) == 0:
+			//a corner where several boundaries meet stays put, as does a vertex no face uses
+			p = m.Vertices[v]
+		default:
+			n := float32(len(neighbours[v]))
+			beta := float32(3) / (8 * n)
+			if len(neighbours[v]) == 3 {
+				beta = 3.0 / 16
+			}
+			p = rays.Multiply(m.Vertices[v], 1-n*beta)
+			for _, u := range neighbours[v] {
+				p = rays.Add(p, rays.Multiply(m.Vertices[u], beta))
+			}
+		}
+		b.vertices[v] = p
+	}
+	for _, key := range order {
+		e := edges[key]
+		e.vertex = b.addMidpoint(key[0], key[1])
+		p := rays.Multiply(rays.Add(m.Vertices[key[0]], m.Vertices[key[1]]), 0.5)
+		if len(e.opposite) == 2 {
+			p = rays.Add(rays.Multiply(rays.Add(m.Vertices[key[0]], m.Vertices[key[1]]), 0.375), rays.Multiply(rays.Add(m.Vertices[e.opposite[0]], m.Vertices[e.opposite[1]]), 0.125))
+		}
+		b.vertices[e.vertex] = p
+	}
+
+	for _, f := range m.Faces {
+		ab, bc, ca := edges[edgeKey(f[0], f[1])].vertex, edges[edgeKey(f[1], f[2])].vertex, edges[edgeKey(f[2], f[0])].vertex
+		b.faces = append(b.faces, [3]int{f[0], ab, ca}, [3]int{ab, f[1], bc}, [3]int{ca, bc, f[2]}, [3]int{ab, bc, ca})
+	}
+	return b.build()
+}
+
+//meshBuilder collects the vertex data of a new mesh made from the vertices of an existing one
+type meshBuilder struct {
+	source   Mesh
+	vertices []rays.Point
+	uvs      [][2]float32
+	normals  []rays.Point
+	colors   []rays.Point
+	faces    [][3]int
+
+	keepNormals bool
+}
+
+//newMeshBuilder starts a mesh copying UVs and colors from source, and normals too if keepNormals is set
+func newMeshBuilder(source Mesh, keepNormals bool) *meshBuilder {
+	b := &meshBuilder{source: source, keepNormals: keepNormals && source.Normals != nil}
+	if source.UVs != nil {
+		b.uvs = [][2]float32{}
+	}
+	if b.keepNormals {
+		b.normals = []rays.Point{}
+	}
+	if source.Colors != nil {
+		b.colors = []rays.Point{}
+	}
+	return b
+}
+
+//addVertex copies vertex i of the source mesh, with whichever attributes are being kept, returning its new index
+func (b *meshBuilder) addVertex(i int) int {
+	b.vertices = append(b.vertices, b.source.Vertices[i])
+	if b.uvs != nil {
+		b.uvs = append(b.uvs, b.source.UVs[i])
+	}
+	if b.keepNormals {
+		b.normals = append(b.normals, b.source.Normals[i])
+	}
+	if b.colors != nil {
+		b.colors = append(b.colors, b.source.Colors[i])
+	}
+	return len(b.vertices) - 1
+}
+
+//addMidpoint adds a vertex halfway between source vertices i and j, returning its new index
+func (b *meshBuilder) addMidpoint(i int, j int) int {
+	src := b.source
+	b.vertices = append(b.vertices, rays.Multiply(rays.Add(src.Vertices[i], src.Vertices[j]), 0.5))
+	if b.uvs != nil {
+		b.uvs = append(b.uvs, [2]float32{(src.UVs[i][0] + src.UVs[j][0]) / 2, (src.UVs[i][1] + src.UVs[j][1]) / 2})
+	}
+	if b.colors != nil {
+		b.colors = append(b.colors, rays.Multiply(rays.Add(src.Colors[i], src.Colors[j]), 0.5))
+	}
+	return len(b.vertices) - 1
+}
+
+//build creates the new mesh, keeping the source's color and material
+func (b *meshBuilder) build() Mesh {
+	m := NewMesh(b.vertices, b.faces, b.source.Color)
+	m.UVs, m.Normals, m.Colors = b.uvs, b.normals, b.colors
+	m.Material = b.source.Material
+	return m
+}
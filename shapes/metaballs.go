@@ -0,0 +1,221 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//wyvillLipschitz is the steepest slope of the Wyvill falloff (1 - r^2/R^2)^3 for a ball of unit radius and weight, reached at r = R/sqrt(5)
+var wyvillLipschitz = float32(6 / math.Sqrt(5) * 16 / 25)
+
+//Metaball is one weighted center of a Metaballs surface.  Like a Circle it has a Center and Radius, but the Radius is where its field fades to zero,
+//so a lone ball's surface lies inside it, closer the lower the threshold is relative to Weight.
+type Metaball struct {
+	Center rays.Point
+	Radius float32
+	Weight float32
+}
+
+//Metaballs is the surface where the summed field of a set of balls equals Threshold, so nearby balls blend into one blob.
+//Each ball's field uses Wyvill's falloff, Weight*(1 - r^2/Radius^2)^3, which is smooth and zero beyond Radius.
+//Ball data lives in a slice, so copies of a Metaballs share it.  Create one with NewMetaballs.
+type Metaballs struct {
+	Balls     []Metaball
+	Threshold float32
+	//Epsilon is the smallest step taken while marching, and how precisely the surface is located
+	Epsilon  float32
+	MaxSteps int
+	Color    rays.Point
+	Material
+	tree   bvh
+	bounds BoundingBox
+}
+
+//NewMetaballs creates the surface where the field of balls equals threshold.  Builds a hierarchy over the balls so rays and points only visit nearby balls.
+func NewMetaballs(balls []Metaball, threshold float32, color rays.Point) Metaballs {
+	m := Metaballs{Balls: balls, Threshold: threshold, Epsilon: 0.01, MaxSteps: 1000, Color: color}
+	boxes := make([]BoundingBox, len(balls))
+	m.bounds = emptyBounds()
+	for i, b := range balls {
+		boxes[i] = BoundingBox{Min: b.Center, Max: b.Center}.Pad(b.Radius)
+		m.bounds = m.bounds.Union(boxes[i])
+	}
+	m.tree = newBVH(boxes)
+	return m
+}
+
+//Equals returns true if the 2 Intersectables are equivalent.  Metaballs are equal when they share the same ball data.
+func (m Metaballs) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Metaballs:
+		compare := i.(Metaballs)
+		if len(m.Balls) == 0 || len(compare.Balls) == 0 {
+			return len(m.Balls) == len(compare.Balls)
+		}
+		return &m.Balls[0] == &compare.Balls[0] && m.Threshold == compare.Threshold
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect finds the first place along the ray the field crosses Threshold.  Only the balls the ray passes through are considered, and the ray
+//is split where it enters or leaves each ball's sphere so every piece is marched with just the balls active along it.  Each piece is marched in
+//steps no longer than the distance the field needs to close its gap to the threshold at its steepest (the Lipschitz bound of the active balls),
+//so no crossing is stepped over, then the crossing is refined by bisection.  The surface is only hit once, so both points returned are the same.
+func (m Metaballs) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	type event struct {
+		t     float32
+		ball  int
+		enter bool
+	}
+	var events []event
+	m.tree.rayItems(r, func(i int) {
+		b := m.Balls[i]
+		o := rays.Subtract(r.Origin, b.Center)
+		t0, t1 := solveQuadratic(rays.DotProduct(r.Direction, r.Direction), 2*rays.DotProduct(o, r.Direction), rays.DotProduct(o, o)-b.Radius*b.Radius)
+		if !math.IsNaN(float64(t1)) && t1 > 0 {
+			events = append(events, event{t: maxFloat(t0, 0), ball: i, enter: true}, event{t: t1, ball: i})
+		}
+	})
+	sort.Slice(events, func(i, j int) bool { return events[i].t < events[j].t })
+
+	speed := rays.Magnitude(r.Direction)
+	var active []int
+	lipschitz := float32(0)
+	for i, e := range events {
+		if e.enter {
+			active = append(active, e.ball)
+			lipschitz += m.Balls[e.ball].lipschitz()
+		} else {
+			for j, b := range active {
+				if b == e.ball {
+					active = append(active[:j], active[j+1:]...)
+					break
+				}
+			}
+			lipschitz -= m.Balls[e.ball].lipschitz()
+		}
+		if len(active) == 0 || i+1 == len(events) || events[i+1].t <= e.t {
+			continue
+		}
+		if t, ok := m.march(r, active, e.t, events[i+1].t, lipschitz*speed); ok {
+			p := rays.Add(r.Origin, rays.Multiply(r.Direction, t))
+			return true, p, p
+		}
+	}
+	return false, intersectPoint0, intersectPoint1
+}
+
+//march steps along r from tStart to tEnd looking for the first threshold crossing of the field of balls, whose slope along the ray is at most lipschitz
+func (m Metaballs) march(r rays.Ray, balls []int, tStart float32, tEnd float32, lipschitz float32) (float32, bool) {
+	gap := func(t float32) float32 {
+		return m.fieldOf(rays.Add(r.Origin, rays.Multiply(r.Direction, t)), balls) - m.Threshold
+	}
+
+	t, g := tStart, gap(tStart)
+	for i := 0; i < m.MaxSteps && t < tEnd; i++ {
+		next := minFloat(t+maxFloat(absFloat(g)/lipschitz, m.Epsilon), tEnd)
+		gNext := gap(next)
+		if (g < 0) != (gNext < 0) {
+			//bisect until the crossing is located to within a fraction of Epsilon, or float precision runs out
+			lo, hi := t, next
+			for j := 0; j < 32 && hi-lo > m.Epsilon*0.01; j++ {
+				mid := (lo + hi) / 2
+				if (gap(mid) < 0) == (g < 0) {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			if hi > hitEpsilon {
+				return hi, true
+			}
+		}
+		t, g = next, gNext
+	}
+	return 0, false
+}
+
+//ColorAtPoint returns the color at a given point.
+func (m Metaballs) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return m.surfaceColor(m.Color, p, m.bounds.Center(), m)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (m Metaballs) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return m.filteredColor(m.Color, p, m.bounds.Center(), m, m.GeometricNormalAtPoint(p).Direction, r)
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
+func (m Metaballs) NormalAtPoint(p rays.Point) rays.Ray {
+	return m.shadingNormal(m.GeometricNormalAtPoint(p), m.bounds.Center(), m)
+}
+
+//GeometricNormalAtPoint returns the inward normal at point p, which is the direction the field increases fastest
+func (m Metaballs) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	var grad rays.Point
+	m.tree.pointItems(p, func(i int) {
+		b := m.Balls[i]
+		rel := rays.Subtract(p, b.Center)
+		s := rays.DotProduct(rel, rel) / (b.Radius * b.Radius)
+		if s < 1 {
+			//derivative of Weight*(1-s)^3 with respect to p, where s = |p-c|^2/R^2
+			grad = rays.Add(grad, rays.Multiply(rel, -6*b.Weight*(1-s)*(1-s)/(b.Radius*b.Radius)))
+		}
+	})
+	return rays.Ray{Origin: p, Direction: normalizeVector(grad)}
+}
+
+//UVAtPoint maps p onto a sphere around the ball contributing the most field at p, the same way a Circle is mapped
+func (m Metaballs) UVAtPoint(p rays.Point) (u float32, v float32) {
+	b := m.Balls[m.strongestBall(p)]
+	return Circle{Center: b.Center, Radius: b.Radius}.UVAtPoint(p)
+}
+
+//Bounds returns the box enclosing every ball's sphere of influence
+func (m Metaballs) Bounds() BoundingBox {
+	return m.bounds
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (m Metaballs) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	b := m.Balls[m.strongestBall(p)]
+	return Circle{Center: b.Center, Radius: b.Radius}.tangentsAtPoint(p)
+}
+
+//fieldOf returns the summed field of balls at point p
+func (m Metaballs) fieldOf(p rays.Point, balls []int) float32 {
+	sum := float32(0)
+	for _, i := range balls {
+		sum += m.Balls[i].field(p)
+	}
+	return sum
+}
+
+//strongestBall returns the index of the ball contributing the most field at p, or 0 if none reach it
+func (m Metaballs) strongestBall(p rays.Point) int {
+	best, strongest := 0, float32(0)
+	m.tree.pointItems(p, func(i int) {
+		if f := m.Balls[i].field(p); f > strongest {
+			best, strongest = i, f
+		}
+	})
+	return best
+}
+
+//lipschitz returns the steepest slope of the ball's field, which a negative Weight makes just as steep downhill
+func (b Metaball) lipschitz() float32 {
+	return absFloat(b.Weight) * wyvillLipschitz / b.Radius
+}
+
+//field returns the ball's contribution at point p
+func (b Metaball) field(p rays.Point) float32 {
+	rel := rays.Subtract(p, b.Center)
+	s := rays.DotProduct(rel, rel) / (b.Radius * b.Radius)
+	if s >= 1 {
+		return 0
+	}
+	return b.Weight * (1 - s) * (1 - s) * (1 - s)
+}
@@ -0,0 +1,29 @@
+package shapes
+
+import (
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+func TestMetaballsSubtractiveBall(t *testing.T) {
+	//the negative ball cancels the positive one's slope, so a signed bound would let the march jump over the blob where both overlap
+	balls := []Metaball{
+		{Center: rays.Point{}, Radius: 2, Weight: 1},
+		{Center: rays.Point{Y: 1.5}, Radius: 2, Weight: -1},
+	}
+	m := NewMetaballs(balls, 0.5, rays.Point{X: 1})
+	r := rays.Ray{Origin: rays.Point{X: -5}, Direction: rays.Point{X: 1}}
+
+	hit, p, _ := m.DoesRayIntersect(r)
+	if !hit {
+		t.Fatal("ray through the blob missed it")
+	}
+	//the negative ball barely reaches the x axis near the surface, so it sits close to the lone ball's, where (1 - x^2/4)^3 = 0.5
+	if p.X > -0.85 || p.X < -0.95 || p.Y != 0 || p.Z != 0 {
+		t.Errorf("hit at %v, want near x = -0.9 on the axis", p)
+	}
+	if g := m.fieldOf(p, []int{0, 1}) - m.Threshold; absFloat(g) > 0.01 {
+		t.Errorf("field at the hit is %v from the threshold", g)
+	}
+}
@@ -1,23 +1,46 @@
 package shapes
 
-import "github.com/flabbergasted/RayTracer/rays"
+import (
+	"errors"
 
-//Plane represents a 2d plane
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//PlaneExtent selects how much of the plane through a Plane's three corners is solid
+type PlaneExtent int
+
+const (
+	//Parallelogram bounds the plane to the parallelogram with edges CornerOne->CornerTwo and CornerOne->CornerThree
+	Parallelogram PlaneExtent = iota
+	//Triangle bounds the plane to the triangle between the three corners
+	Triangle
+	//Infinite extends the plane forever in every direction
+	Infinite
+)
+
+//Plane represents a 2d plane through three corners, either bounded by them or infinite
 type Plane struct {
 	CornerOne   rays.Point
 	CornerTwo   rays.Point
 	CornerThree rays.Point
 	Color       rays.Point
-	normal      rays.Ray
+	Extent      PlaneExtent
+	Material
+	normal rays.Ray
 }
 
 //NewPlane creates a new plane with the provided information.  Precalculates normal for performance.
-func NewPlane(corner1 rays.Point, corner2 rays.Point, corner3 rays.Point, color rays.Point) Plane {
-	p := Plane{Color: color, CornerOne: corner1, CornerTwo: corner2, CornerThree: corner3}
+//Returns an error if the corners are collinear, since they then do not define a plane.
+func NewPlane(corner1 rays.Point, corner2 rays.Point, corner3 rays.Point, color rays.Point, extent PlaneExtent) (Plane, error) {
+	p := Plane{Color: color, CornerOne: corner1, CornerTwo: corner2, CornerThree: corner3, Extent: extent}
 
-	p.normal = calcNormal(p)
+	normal, err := calcNormal(p)
+	if err != nil {
+		return p, err
+	}
+	p.normal = normal
 
-	return p
+	return p, nil
 }
 
 //Equals returns true if the 2 Intersectables are equivalent
@@ -25,7 +48,7 @@ func (pn Plane) Equals(i Intersectable) bool {
 	switch i.(type) {
 	case Plane:
 		compare := i.(Plane)
-		return pn.CornerOne.Equals(compare.CornerOne) && pn.CornerTwo.Equals(compare.CornerTwo) && pn.CornerThree.Equals(compare.CornerThree)
+		return pn.CornerOne.Equals(compare.CornerOne) && pn.CornerTwo.Equals(compare.CornerTwo) && pn.CornerThree.Equals(compare.CornerThree) && pn.Extent == compare.Extent
 	default:
 		return false
 	}
@@ -34,7 +57,7 @@ func (pn Plane) Equals(i Intersectable) bool {
 //DoesRayIntersect performs the ray intersection described here: https://www.scratchapixel.com/lessons/3d-basic-rendering/minimal-ray-tracer-rendering-simple-shapes/ray-plane-and-ray-disk-intersection
 func (pn Plane) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
 	var p0 rays.Point
-	surfaceNormal := pn.NormalAtPoint(intersectPoint0)
+	surfaceNormal := pn.normal
 	denom := rays.DotProduct(surfaceNormal.Direction, r.Direction)
 
 	if denom < 1e-6 {
@@ -48,24 +71,91 @@ func (pn Plane) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint
 		return false, p0, p0
 	}
 	p0 = rays.Add(r.Origin, rays.Multiply(r.Direction, t))
+	if !pn.withinExtent(p0) {
+		return false, rays.Point{}, rays.Point{}
+	}
 	return true, p0, p0
 }
 
+//withinExtent returns true if point p, which must lie on the plane, is inside the plane's bounds
+func (pn Plane) withinExtent(p rays.Point) bool {
+	if pn.Extent == Infinite {
+		return true
+	}
+	u, v := pn.UVAtPoint(p)
+	if u < 0 || v < 0 {
+		return false
+	}
+	if pn.Extent == Triangle {
+		return u+v <= 1
+	}
+	return u <= 1 && v <= 1
+}
+
 //ColorAtPoint returns the color at a given point.
 func (pn Plane) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
-	return pn.Color
+	return pn.surfaceColor(pn.Color, p, pn.CornerOne, pn)
 }
 
-//NormalAtPoint returns the surface normal for this intersectable shape at point p
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (pn Plane) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return pn.filteredColor(pn.Color, p, pn.CornerOne, pn, pn.normal.Direction, r)
+}
+
+//UVAtPoint returns the planar projection of p onto the edges CornerOne->CornerTwo (u) and CornerOne->CornerThree (v).
+//Points inside the parallelogram spanned by the three corners map to the range [0,1].
+func (pn Plane) UVAtPoint(p rays.Point) (u float32, v float32) {
+	e1 := rays.Subtract(pn.CornerTwo, pn.CornerOne)
+	e2 := rays.Subtract(pn.CornerThree, pn.CornerOne)
+	d := rays.Subtract(p, pn.CornerOne)
+
+	d11, d12, d22 := rays.DotProduct(e1, e1), rays.DotProduct(e1, e2), rays.DotProduct(e2, e2)
+	d1, d2 := rays.DotProduct(d, e1), rays.DotProduct(d, e2)
+	denom := d11*d22 - d12*d12
+
+	u = (d22*d1 - d12*d2) / denom
+	v = (d11*d2 - d12*d1) / denom
+	return u, v
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
 func (pn Plane) NormalAtPoint(p rays.Point) rays.Ray {
-	return pn.normal
+	if pn.NormalMap == nil && pn.BumpMap == nil {
+		return pn.normal
+	}
+	return pn.shadingNormal(pn.GeometricNormalAtPoint(p), pn.CornerOne, pn)
 }
 
-func calcNormal(pl Plane) rays.Ray {
-	r1 := rays.Ray{Origin: pl.CornerOne}
-	r2 := rays.Ray{Origin: pl.CornerOne}
-	r1.Direction = rays.Subtract(pl.CornerOne, pl.CornerTwo)
-	r2.Direction = rays.Subtract(pl.CornerOne, pl.CornerThree)
+//GeometricNormalAtPoint returns the true surface normal of the plane, which is the same everywhere
+func (pn Plane) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	return rays.Ray{Origin: p, Direction: pn.normal.Direction}
+}
 
-	return rays.CrossProduct(r1, r2)
+//Bounds returns the box enclosing the plane's corners, or an infinite box if the plane extends forever
+func (pn Plane) Bounds() BoundingBox {
+	if pn.Extent == Infinite {
+		return infiniteBounds()
+	}
+	b := BoundingBox{Min: pn.CornerOne, Max: pn.CornerOne}.Extend(pn.CornerTwo).Extend(pn.CornerThree)
+	if pn.Extent == Parallelogram {
+		b = b.Extend(rays.Subtract(rays.Add(pn.CornerTwo, pn.CornerThree), pn.CornerOne))
+	}
+	return b
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase, which are the plane's two edges
+func (pn Plane) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	return rays.Subtract(pn.CornerTwo, pn.CornerOne), rays.Subtract(pn.CornerThree, pn.CornerOne)
+}
+
+func calcNormal(pl Plane) (rays.Ray, error) {
+	e1 := rays.Subtract(pl.CornerOne, pl.CornerTwo)
+	e2 := rays.Subtract(pl.CornerOne, pl.CornerThree)
+	n := cross(e1, e2)
+
+	//the cross product of collinear edges has no length, compare against the edge lengths so the test does not depend on the plane's size
+	if rays.Magnitude(n) <= 1e-6*rays.Magnitude(e1)*rays.Magnitude(e2) {
+		return rays.Ray{}, errors.New("plane corners are collinear")
+	}
+	return rays.Ray{Origin: pl.CornerOne, Direction: normalizeVector(n)}, nil
 }
@@ -0,0 +1,200 @@
+package shapes
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//PointShape selects how each point of a PointCloud is drawn
+type PointShape int
+
+const (
+	//SpherePoints draws every point as a sphere
+	SpherePoints PointShape = iota
+	//DiskPoints draws every point as a flat disk turned to face the PointCloud's Camera, which is cheaper and shows no shading across the point
+	DiskPoints
+)
+
+//PointCloud draws a large set of points, such as a LiDAR capture, as small spheres or camera facing disks.  Points are kept in flat slices and
+//found through a hierarchy, so millions of them cost far less than a Circle each.  Colors are optional and hold one color per point, passed to
+//textures as the sample's VertexColor (see textures.VertexColor).  Point data lives in slices, so copies of a PointCloud share it.
+//Create one with NewPointCloud.
+type PointCloud struct {
+	Points []rays.Point
+	Radii  []float32 //one radius per point, or a single radius shared by every point
+	Colors []rays.Point
+	Shape  PointShape
+	Camera rays.Point //position DiskPoints turn to face
+	Color  rays.Point
+	Material
+	tree   bvh
+	bounds BoundingBox
+}
+
+//NewPointCloud creates a cloud of points with radii holding either one radius per point or a single radius for all of them.
+//Builds a hierarchy over the points, so the points and radii must not change afterwards.
+func NewPointCloud(points []rays.Point, radii []float32, color rays.Point) (PointCloud, error) {
+	if len(points) == 0 {
+		return PointCloud{}, fmt.Errorf("point cloud has no points")
+	}
+	if len(radii) != 1 && len(radii) != len(points) {
+		return PointCloud{}, fmt.Errorf("point cloud has %d radii for %d points, it needs one each or a single shared radius", len(radii), len(points))
+	}
+
+	pc := PointCloud{Points: points, Radii: radii, Color: color, bounds: emptyBounds()}
+	boxes := make([]BoundingBox, len(points))
+	for i, p := range points {
+		//the sphere's box also holds a disk of the same radius facing any direction
+		boxes[i] = BoundingBox{Min: p, Max: p}.Pad(pc.radius(i) + meshEpsilon*10)
+		pc.bounds = pc.bounds.Union(boxes[i])
+	}
+	pc.tree = newBVH(boxes)
+	return pc, nil
+}
+
+//Equals returns true if the 2 Intersectables are equivalent.  Point clouds are equal when they share the same point data.
+func (pc PointCloud) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case PointCloud:
+		compare := i.(PointCloud)
+		if len(pc.Points) == 0 || len(compare.Points) == 0 {
+			return len(pc.Points) == len(compare.Points)
+		}
+		return &pc.Points[0] == &compare.Points[0] && pc.Shape == compare.Shape
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect finds the nearest point the ray hits.  intersectPoint1 is where the ray leaves that point, the same as the hit for a disk.
+func (pc PointCloud) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	near, far := float32(math.MaxFloat32), float32(0)
+	pc.tree.rayItems(r, func(i int) {
+		t0, t1, hit := pc.intersectPoint(i, r)
+		if hit && t0 < near {
+			near, far = t0, t1
+		}
+	})
+	if near == math.MaxFloat32 {
+		return false, intersectPoint0, intersectPoint1
+	}
+	return true, rays.Add(r.Origin, rays.Multiply(r.Direction, near)), rays.Add(r.Origin, rays.Multiply(r.Direction, far))
+}
+
+//intersectPoint returns the distances along r where it enters and leaves point i
+func (pc PointCloud) intersectPoint(i int, r rays.Ray) (t0 float32, t1 float32, hit bool) {
+	c, radius := pc.Points[i], pc.radius(i)
+	if pc.Shape == DiskPoints {
+		n := normalizeVector(rays.Subtract(c, pc.Camera))
+		dn := rays.DotProduct(r.Direction, n)
+		if dn == 0 {
+			return 0, 0, false
+		}
+		t := rays.DotProduct(rays.Subtract(c, r.Origin), n) / dn
+		if t <= hitEpsilon || rays.Magnitude(rays.Subtract(rays.Add(r.Origin, rays.Multiply(r.Direction, t)), c)) > radius {
+			return 0, 0, false
+		}
+		return t, t, true
+	}
+
+	o := rays.Subtract(r.Origin, c)
+	t0, t1 = solveQuadratic(rays.DotProduct(r.Direction, r.Direction), 2*rays.DotProduct(o, r.Direction), rays.DotProduct(o, o)-radius*radius)
+	if math.IsNaN(float64(t1)) || t1 <= hitEpsilon {
+		return 0, 0, false
+	}
+	if t0 <= hitEpsilon {
+		//the ray starts inside this point
+		return t1, t1, true
+	}
+	return t0, t1, true
+}
+
+//ColorAtPoint returns the color at a given point.
+func (pc PointCloud) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return pc.surfaceColor(pc.Color, p, pc.bounds.Center(), pc)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (pc PointCloud) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return pc.filteredColor(pc.Color, p, pc.bounds.Center(), pc, pc.GeometricNormalAtPoint(p).Direction, r)
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
+func (pc PointCloud) NormalAtPoint(p rays.Point) rays.Ray {
+	return pc.shadingNormal(pc.GeometricNormalAtPoint(p), pc.bounds.Center(), pc)
+}
+
+//GeometricNormalAtPoint returns the inward normal of the point p lies on: towards a sphere's center, or away from the camera for a disk
+func (pc PointCloud) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	i := pc.pointAt(p)
+	if i < 0 {
+		return rays.Ray{Origin: p}
+	}
+	if pc.Shape == DiskPoints {
+		return rays.Ray{Origin: p, Direction: normalizeVector(rays.Subtract(pc.Points[i], pc.Camera))}
+	}
+	return rays.Ray{Origin: p, Direction: normalizeVector(rays.Subtract(pc.Points[i], p))}
+}
+
+//UVAtPoint maps p onto the sphere around the point it lies on, the same way a Circle is mapped
+func (pc PointCloud) UVAtPoint(p rays.Point) (u float32, v float32) {
+	i := pc.pointAt(p)
+	if i < 0 {
+		return 0, 0
+	}
+	return Circle{Center: pc.Points[i], Radius: pc.radius(i)}.UVAtPoint(p)
+}
+
+//VertexColorAtPoint returns the color of the point p lies on.  ok is false if the cloud has no colors.
+func (pc PointCloud) VertexColorAtPoint(p rays.Point) (color rays.Point, ok bool) {
+	i := pc.pointAt(p)
+	if i < 0 || len(pc.Colors) == 0 {
+		return color, false
+	}
+	return pc.Colors[i], true
+}
+
+//Bounds returns the box enclosing every point
+func (pc PointCloud) Bounds() BoundingBox {
+	return pc.bounds
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (pc PointCloud) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	i := pc.pointAt(p)
+	if i < 0 {
+		return dpdu, dpdv
+	}
+	return Circle{Center: pc.Points[i], Radius: pc.radius(i)}.tangentsAtPoint(p)
+}
+
+//radius returns the radius of point i
+func (pc PointCloud) radius(i int) float32 {
+	if len(pc.Radii) == 1 {
+		return pc.Radii[0]
+	}
+	return pc.Radii[i]
+}
+
+//pointAt returns the index of the point whose surface p lies closest to, or -1 if p is not near any
+func (pc PointCloud) pointAt(p rays.Point) int {
+	best, found := float32(math.MaxFloat32), -1
+	pc.tree.pointItems(p, func(i int) {
+		offset := rays.Subtract(p, pc.Points[i])
+		dist := absFloat(rays.Magnitude(offset) - pc.radius(i))
+		if pc.Shape == DiskPoints {
+			//distance from the disk's plane, for points within its radius
+			n := normalizeVector(rays.Subtract(pc.Points[i], pc.Camera))
+			dist = absFloat(rays.DotProduct(offset, n))
+			if rays.Magnitude(offset) > pc.radius(i)+meshEpsilon*10 {
+				return
+			}
+		}
+		if dist < best {
+			best, found = dist, i
+		}
+	})
+	return found
+}
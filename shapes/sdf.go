@@ -0,0 +1,163 @@
+package shapes
+
+import (
+	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/sdf"
+)
+
+//SDFShape is a surface defined by a signed distance function, intersected by sphere tracing: stepping along the ray by the distance to the
+//nearest surface until it comes within Epsilon of it.  See: https://iquilezles.org/articles/raymarchingdf/
+//Create one with NewSDFShape, then adjust the marching settings if the defaults do not suit the distance function.
+type SDFShape struct {
+	Distance sdf.Distance
+	//Box limits marching to the region the surface lies in, leave it zero if the surface is unbounded
+	Box BoundingBox
+	//Epsilon is how close to the surface a ray must come to hit it, and the offset used to estimate normals
+	Epsilon float32
+	//MaxSteps and MaxDistance limit how far a ray is marched before it is counted as a miss
+	MaxSteps    int
+	MaxDistance float32
+	//StepScale is the fraction of the distance each step advances, set it below 1 for distance functions that overestimate (smooth operators, fractals)
+	StepScale float32
+	Color     rays.Point
+	Material
+	key *int
+}
+
+//NewSDFShape creates a shape from the distance function, bounded by box (zero for unbounded), with default marching settings
+func NewSDFShape(distance sdf.Distance, box BoundingBox, color rays.Point) SDFShape {
+	return SDFShape{
+		Distance:    distance,
+		Box:         box,
+		Epsilon:     0.01,
+		MaxSteps:    256,
+		MaxDistance: 100000,
+		StepScale:   1,
+		Color:       color,
+		key:         new(int),
+	}
+}
+
+//NewFractalShape creates a shape from a distance estimated fractal, bounded by the fractal's own bounds.
+//Fractal distance estimates are not exact, so it marches with shorter steps and a larger step budget than NewSDFShape.
+func NewFractalShape(f sdf.Fractal, color rays.Point) SDFShape {
+	min, max := f.Bounds()
+	s := NewSDFShape(f.Distance, BoundingBox{Min: min, Max: max}, color)
+	s.StepScale = 0.5
+	s.MaxSteps = 1024
+	return s
+}
+
+//Equals returns true if the 2 Intersectables are equivalent.  Distance functions cannot be compared, so only copies of the same SDFShape are equal.
+func (s SDFShape) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case SDFShape:
+		compare := i.(SDFShape)
+		return s.key == compare.key
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect sphere traces along the ray through the shape's bounds.  The surface is only hit once, so both points returned are the same.
+func (s SDFShape) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	tNear, tFar, hit := s.Bounds().Intersect(r)
+	if !hit {
+		return false, intersectPoint0, intersectPoint1
+	}
+	t, tMax := maxFloat(tNear, 0), minFloat(tFar, s.MaxDistance)
+
+	//rays starting on the surface, like shadow rays, must move clear of it before a hit counts
+	leaving := t == 0
+	for i := 0; i < s.MaxSteps && t <= tMax; i++ {
+		p := rays.Add(r.Origin, rays.Multiply(r.Direction, t))
+		d := absFloat(s.Distance(p))
+		if d < s.Epsilon {
+			if !leaving {
+				return true, p, p
+			}
+			t += s.Epsilon
+			continue
+		}
+		leaving = false
+		t += d * s.StepScale
+	}
+	return false, intersectPoint0, intersectPoint1
+}
+
+//ColorAtPoint returns the color at a given point.
+func (s SDFShape) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return s.surfaceColor(s.Color, p, s.Box.Min, s)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (s SDFShape) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return s.filteredColor(s.Color, p, s.Box.Min, s, s.GeometricNormalAtPoint(p).Direction, r)
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
+func (s SDFShape) NormalAtPoint(p rays.Point) rays.Ray {
+	return s.shadingNormal(s.GeometricNormalAtPoint(p), s.Box.Min, s)
+}
+
+//GeometricNormalAtPoint returns the inward normal at point p, the negated gradient of the distance function estimated by central differences
+func (s SDFShape) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	return rays.Ray{Origin: p, Direction: rays.Multiply(distanceGradient(s.Distance, p, s.Epsilon), -1)}
+}
+
+//UVAtPoint projects p onto the plane facing the axis the surface faces most, scaled so the bounds cover [0,1]
+func (s SDFShape) UVAtPoint(p rays.Point) (u float32, v float32) {
+	rel, size := p, rays.Point{X: 1, Y: 1, Z: 1}
+	if b := s.Bounds(); !b.IsInfinite() {
+		rel, size = rays.Subtract(p, b.Min), rays.Subtract(b.Max, b.Min)
+	}
+	switch dominantAxis(s.GeometricNormalAtPoint(p).Direction) {
+	case 0:
+		return rel.Z / size.Z, rel.Y / size.Y
+	case 1:
+		return rel.X / size.X, rel.Z / size.Z
+	default:
+		return rel.X / size.X, rel.Y / size.Y
+	}
+}
+
+//Bounds returns Box, or an infinite box if it is not set
+func (s SDFShape) Bounds() BoundingBox {
+	if s.Box == (BoundingBox{}) {
+		return infiniteBounds()
+	}
+	return s.Box
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (s SDFShape) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	switch dominantAxis(s.GeometricNormalAtPoint(p).Direction) {
+	case 0:
+		return rays.Point{Z: 1}, rays.Point{Y: 1}
+	case 1:
+		return rays.Point{X: 1}, rays.Point{Z: 1}
+	default:
+		return rays.Point{X: 1}, rays.Point{Y: 1}
+	}
+}
+
+//distanceGradient returns the normalized gradient of distance function d at p, estimated by central differences h apart.
+//It points away from the surface, out of the solid.
+func distanceGradient(d sdf.Distance, p rays.Point, h float32) rays.Point {
+	return normalizeVector(rays.Point{
+		X: d(rays.Point{X: p.X + h, Y: p.Y, Z: p.Z}) - d(rays.Point{X: p.X - h, Y: p.Y, Z: p.Z}),
+		Y: d(rays.Point{X: p.X, Y: p.Y + h, Z: p.Z}) - d(rays.Point{X: p.X, Y: p.Y - h, Z: p.Z}),
+		Z: d(rays.Point{X: p.X, Y: p.Y, Z: p.Z + h}) - d(rays.Point{X: p.X, Y: p.Y, Z: p.Z - h})})
+}
+
+//dominantAxis returns the axis (0=X, 1=Y, 2=Z) of n's largest component
+func dominantAxis(n rays.Point) int {
+	x, y, z := absFloat(n.X), absFloat(n.Y), absFloat(n.Z)
+	if x >= y && x >= z {
+		return 0
+	}
+	if y >= z {
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,193 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//subsurfaceSamples is how many points around a hit are lit when gathering the light that scatters beneath the surface to it
+const subsurfaceSamples = 32
+
+//Subsurface makes a shape, such as a Circle or Mesh, translucent like skin, wax or marble: light entering the surface scatters around inside
+//and leaves again some distance away, softening the shading and bleeding color into the shadows.  It uses the dipole approximation of the
+//BSSRDF from Jensen et al., "A Practical Model for Subsurface Light Transport".  When lit (see NewLightSourceCircle), the light reaching the
+//surface around each point is gathered and spread by the dipole's diffuse profile, tinted by the inner shape's color.
+//MeanFreePath is the average distance light travels between scattering inside, per color channel in world units, and Albedo the fraction of
+//light kept at each scattering event, per color channel.  Skin scatters red furthest, and wax and marble have albedos close to 1.
+type Subsurface struct {
+	Inner        Intersectable
+	MeanFreePath rays.Point
+	Albedo       rays.Point
+	IOR          float32 //index of refraction of the material, 1.3 if zero
+}
+
+//Equals returns true if the 2 Intersectables are equivalent
+func (s Subsurface) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Subsurface:
+		compare := i.(Subsurface)
+		return s.Inner.Equals(compare.Inner)
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect forwards the call to the inner shape
+func (s Subsurface) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	return s.Inner.DoesRayIntersect(r)
+}
+
+//ColorAtPoint forwards the call to the inner shape
+func (s Subsurface) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return s.Inner.ColorAtPoint(p, cameraPosition)
+}
+
+//ColorAtPointDifferential forwards the call to the inner shape, keeping the ray's differentials for texture filtering
+func (s Subsurface) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return ColorAt(s.Inner, p, r)
+}
+
+//NormalAtPoint forwards the call to the inner shape
+func (s Subsurface) NormalAtPoint(p rays.Point) rays.Ray {
+	return s.Inner.NormalAtPoint(p)
+}
+
+//GeometricNormalAtPoint forwards the call to the inner shape
+func (s Subsurface) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	return GeometricNormal(s.Inner, p)
+}
+
+//Bounds forwards the call to the inner shape
+func (s Subsurface) Bounds() BoundingBox {
+	return BoundsOf(s.Inner)
+}
+
+//UVAtPoint forwards the call to the inner shape, if it provides a surface parameterization
+func (s Subsurface) UVAtPoint(p rays.Point) (u float32, v float32) {
+	if uv, ok := s.Inner.(UVMapper); ok {
+		return uv.UVAtPoint(p)
+	}
+	return 0, 0
+}
+
+//dipole holds the dipole's constants for one color channel
+type dipole struct {
+	albedo  float32
+	sigmaT  float32 //extinction, the reciprocal of the mean free path
+	sigmaTr float32 //effective transport extinction, how quickly the diffuse light dies away with distance
+	zr      float32 //depth of the real light source below the surface
+	zv      float32 //height of the mirrored virtual light source above it
+	a       float32 //boundary term for the light reflected back in at the surface
+}
+
+//newDipole returns the dipole for a channel with the given mean free path and albedo, beneath a surface with index of refraction eta
+func newDipole(meanFreePath float32, albedo float32, eta float32) dipole {
+	//an albedo of 1 would absorb nothing and spread the light endlessly
+	d := dipole{albedo: clampFloat(albedo, 0, 0.999), sigmaT: 1 / maxFloat(meanFreePath, 1e-6)}
+	sigmaA := d.sigmaT * (1 - d.albedo)
+	d.sigmaTr = float32(math.Sqrt(float64(3 * sigmaA * d.sigmaT)))
+
+	//diffuse Fresnel reflectance, fitted by Egan and Hilgeman
+	fdr := -1.440/(eta*eta) + 0.710/eta + 0.668 + 0.0636*eta
+	d.a = (1 + fdr) / (1 - fdr)
+	d.zr = 1 / d.sigmaT
+	d.zv = d.zr * (1 + 4*d.a/3)
+	return d
+}
+
+//profile returns the diffuse reflectance Rd of the dipole, the fraction of light entering the surface that leaves it per unit area at
+//distance r from where it entered
+func (d dipole) profile(r float32) float32 {
+	term := func(z float32) float32 {
+		dist := float32(math.Sqrt(float64(r*r + z*z)))
+		return z * (d.sigmaTr*dist + 1) * float32(math.Exp(float64(-d.sigmaTr*dist))) / (dist * dist * dist)
+	}
+	return d.albedo / (4 * math.Pi) * (term(d.zr) + term(d.zv))
+}
+
+//reflectance returns the profile integrated over the whole surface, the total fraction of light that enters and leaves again
+func (d dipole) reflectance() float32 {
+	s := float32(math.Sqrt(float64(3 * (1 - d.albedo))))
+	return d.albedo / 2 * (1 + float32(math.Exp(float64(-4*d.a*s/3)))) * float32(math.Exp(float64(-s)))
+}
+
+//dipoles returns the dipole for each color channel
+func (s Subsurface) dipoles() [3]dipole {
+	eta := s.IOR
+	if eta == 0 {
+		eta = 1.3
+	}
+	return [3]dipole{
+		newDipole(s.MeanFreePath.X, s.Albedo.X, eta),
+		newDipole(s.MeanFreePath.Y, s.Albedo.Y, eta),
+		newDipole(s.MeanFreePath.Z, s.Albedo.Z, eta),
+	}
+}
+
+//radiance returns the light from light leaving the surface at p towards viewer after scattering beneath it, plus the ambient light scattered
+//the same way.  The surface around p is found by probing the inner shape along the normal at points scattered over the tangent plane, spread
+//as far as each channel diffuses.  A thick flat surface lit evenly head on gives back the dipole's total diffuse reflectance.
+func (s Subsurface) radiance(p rays.Point, viewer rays.Point, light rays.Point, l Lighting) rays.Point {
+	channels := s.dipoles()
+	reflectance := rays.Point{X: channels[0].reflectance(), Y: channels[1].reflectance(), Z: channels[2].reflectance()}
+	ambient := rays.Multiply(reflectance, ambientAdjust)
+
+	//normals point inwards, the outward one is turned to face the viewer so meshes wound either way work
+	n := rays.Multiply(normalizeVector(s.Inner.NormalAtPoint(p).Direction), -1)
+	if rays.DotProduct(n, rays.Subtract(viewer, p)) < 0 {
+		n = rays.Multiply(n, -1)
+	}
+	helper := rays.Point{X: 1}
+	if math.Abs(float64(n.X)) > 0.9 {
+		helper = rays.Point{Y: 1}
+	}
+	tangent := normalizeVector(cross(helper, n))
+	bitangent := cross(n, tangent)
+
+	var gathered rays.Point
+	random := newRayRandom(rays.Ray{Origin: p, Direction: rays.Subtract(p, viewer)}, 0)
+	for i := 0; i < subsurfaceSamples; i++ {
+		//each channel in turn picks the radius, drawn with density sigmaTr*exp(-sigmaTr*radius) so samples gather where it diffuses, angles evenly
+		radius := -float32(math.Log(float64(1-random()))) / channels[i%3].sigmaTr
+		angle := 2 * math.Pi * float64(random())
+		offset := rays.Add(rays.Multiply(tangent, radius*float32(math.Cos(angle))), rays.Multiply(bitangent, radius*float32(math.Sin(angle))))
+		height := radius + meshEpsilon
+		probe := rays.Ray{Origin: rays.Add(rays.Add(p, offset), rays.Multiply(n, height)), Direction: rays.Multiply(n, -1)}
+		hit, sample, _ := s.Inner.DoesRayIntersect(probe)
+		if !hit || rays.Magnitude(rays.Subtract(sample, probe.Origin)) > 2*height {
+			continue
+		}
+
+		sampleNormal := rays.Multiply(normalizeVector(s.Inner.NormalAtPoint(sample).Direction), -1)
+		if rays.DotProduct(sampleNormal, n) < 0 {
+			sampleNormal = rays.Multiply(sampleNormal, -1)
+		}
+		cosine := rays.DotProduct(sampleNormal, rays.Normalize(sample, light))
+		if cosine <= 0 {
+			continue
+		}
+		irradiance := rays.Multiply(lightTransmittance(sample, light, l), cosine)
+
+		//each sample stands for the area of the tangent plane its probability density gives it
+		r := rays.Magnitude(rays.Subtract(sample, p))
+		var density float32
+		for _, c := range channels {
+			density += c.sigmaTr * float32(math.Exp(float64(-c.sigmaTr*radius))) / (2 * math.Pi * radius) / 3
+		}
+		if density == 0 {
+			//so far out that no channel's profile reaches back
+			continue
+		}
+		area := 1 / density
+		profile := rays.Point{X: channels[0].profile(r), Y: channels[1].profile(r), Z: channels[2].profile(r)}
+		gathered = rays.Add(gathered, rays.Multiply(multiplyColors(profile, irradiance), area))
+	}
+	return rays.Add(ambient, rays.Multiply(gathered, (1-ambientAdjust)/subsurfaceSamples))
+}
+
+//subsurfaceLight lights a Subsurface shape by the light scattered beneath its surface, tinted by the inner shape's color
+func subsurfaceLight(p rays.Point, r rays.RayDifferential, l Lighting) rays.Point {
+	s := l.Inner.(Subsurface)
+	return multiplyColors(ColorAt(s.Inner, p, r), s.radiance(p, r.Origin, l.LightSource, l))
+}
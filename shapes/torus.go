@@ -0,0 +1,125 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Torus represents a ring around Center lying in the XZ plane.  MajorRadius is the distance from Center to the middle of the tube
+//and MinorRadius is the radius of the tube.  Use an Instance to orient it around another axis.
+type Torus struct {
+	Center      rays.Point
+	MajorRadius float32
+	MinorRadius float32
+	Color       rays.Point
+	Material
+}
+
+//Equals returns true if the 2 Intersectables are equivalent
+func (tr Torus) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Torus:
+		compare := i.(Torus)
+		return tr.Center.Equals(compare.Center) && tr.MajorRadius == compare.MajorRadius && tr.MinorRadius == compare.MinorRadius
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect solves the quartic (x^2 + y^2 + z^2 + R^2 - r^2)^2 = 4R^2(x^2 + z^2) along the ray
+func (tr Torus) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	return nearestHits(r, tr.hitDistances(r)...)
+}
+
+//Intervals returns the spans ray r spends inside the torus, there are two if it passes through the hole
+func (tr Torus) Intervals(r rays.Ray) []Interval {
+	return pairIntervals(tr.hitDistances(r))
+}
+
+//hitDistances returns the ray distances of every crossing of the torus' surface, including those behind the ray origin, in ascending order
+func (tr Torus) hitDistances(r rays.Ray) []float32 {
+	tNear, _, hit := tr.Bounds().Intersect(r)
+	if !hit {
+		return nil
+	}
+	//start from where the ray enters the bounds so the roots stay small, the quartic loses precision quickly as they grow
+	start := tNear
+
+	o := rays.Subtract(rays.Add(r.Origin, rays.Multiply(r.Direction, start)), tr.Center)
+	ox, oy, oz := float64(o.X), float64(o.Y), float64(o.Z)
+	dx, dy, dz := float64(r.Direction.X), float64(r.Direction.Y), float64(r.Direction.Z)
+	R2 := float64(tr.MajorRadius) * float64(tr.MajorRadius)
+	r2 := float64(tr.MinorRadius) * float64(tr.MinorRadius)
+
+	dd := dx*dx + dy*dy + dz*dz
+	od := ox*dx + oy*dy + oz*dz
+	e := ox*ox + oy*oy + oz*oz - R2 - r2
+
+	roots := rays.SolveQuartic(
+		dd*dd,
+		4*dd*od,
+		2*dd*e+4*od*od+4*R2*dy*dy,
+		4*od*e+8*R2*oy*dy,
+		e*e-4*R2*(r2-oy*oy))
+
+	res := make([]float32, 0, len(roots))
+	for _, t := range roots {
+		if t >= 0 {
+			res = append(res, float32(t)+start)
+		}
+	}
+	return res
+}
+
+//ColorAtPoint returns the color at a given point.
+func (tr Torus) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return tr.surfaceColor(tr.Color, p, tr.Center, tr)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (tr Torus) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return tr.filteredColor(tr.Color, p, tr.Center, tr, tr.GeometricNormalAtPoint(p).Direction, r)
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
+func (tr Torus) NormalAtPoint(p rays.Point) rays.Ray {
+	return tr.shadingNormal(tr.GeometricNormalAtPoint(p), tr.Center, tr)
+}
+
+//GeometricNormalAtPoint returns the inward normal at point p, pointing from p towards the nearest point on the circle through the middle of the tube
+func (tr Torus) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	rel := rays.Subtract(p, tr.Center)
+	return rays.Ray{Origin: p, Direction: normalizeVector(rays.Subtract(tr.coreAtPoint(rel), rel))}
+}
+
+//UVAtPoint wraps u around the ring and v around the tube, starting from its inside edge
+func (tr Torus) UVAtPoint(p rays.Point) (u float32, v float32) {
+	rel := rays.Subtract(p, tr.Center)
+	rho := math.Sqrt(float64(rel.X*rel.X + rel.Z*rel.Z))
+	u = 0.5 + float32(math.Atan2(float64(rel.Z), float64(rel.X))/(2*math.Pi))
+	v = 0.5 + float32(math.Atan2(float64(rel.Y), rho-float64(tr.MajorRadius))/(2*math.Pi))
+	return u, v
+}
+
+//Bounds returns the box enclosing the torus
+func (tr Torus) Bounds() BoundingBox {
+	outer := tr.MajorRadius + tr.MinorRadius
+	return BoundingBox{
+		Min: rays.Subtract(tr.Center, rays.Point{X: outer, Y: tr.MinorRadius, Z: outer}),
+		Max: rays.Add(tr.Center, rays.Point{X: outer, Y: tr.MinorRadius, Z: outer})}
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (tr Torus) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	rel := rays.Subtract(p, tr.Center)
+	outward := rays.Subtract(rel, tr.coreAtPoint(rel))
+	dpdu = rays.Point{X: -rel.Z, Z: rel.X}
+	return dpdu, cross(dpdu, outward)
+}
+
+//coreAtPoint returns the point on the circle through the middle of the tube nearest to rel, given relative to Center
+func (tr Torus) coreAtPoint(rel rays.Point) rays.Point {
+	radial := normalizeVector(rays.Point{X: rel.X, Z: rel.Z})
+	return rays.Multiply(radial, tr.MajorRadius)
+}
@@ -0,0 +1,57 @@
+package shapes
+
+import (
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//testTorus lies in the XZ plane around the origin, its tube spanning 8 to 12 from the center
+var testTorus = Torus{MajorRadius: 10, MinorRadius: 2}
+
+func TestTorusGrazingRay(t *testing.T) {
+	//tangent to the outer equator at (12, 0, 0)
+	r := rays.Ray{Origin: rays.Point{X: 12, Z: -50}, Direction: rays.Point{Z: 1}}
+	hit, p, _ := testTorus.DoesRayIntersect(r)
+	if !hit {
+		t.Fatal("grazing ray missed the torus")
+	}
+	if d := rays.Magnitude(rays.Subtract(p, rays.Point{X: 12})); d > 1e-2 {
+		t.Errorf("grazing ray hit at %v, want (12, 0, 0)", p)
+	}
+}
+
+func TestTorusRayThroughHole(t *testing.T) {
+	//along the x axis, through both sides of the tube and the hole between them
+	r := rays.Ray{Origin: rays.Point{X: -50}, Direction: rays.Point{X: 1}}
+	got := testTorus.hitDistances(r)
+	want := []float32{38, 42, 58, 62}
+	if len(got) != len(want) {
+		t.Fatalf("hitDistances = %v, want %v", got, want)
+	}
+	for i := range want {
+		if absFloat(got[i]-want[i]) > 1e-3 {
+			t.Errorf("hitDistances = %v, want %v", got, want)
+			break
+		}
+	}
+
+	if in := testTorus.Intervals(r); len(in) != 2 {
+		t.Errorf("Intervals = %v, want 2 spans", in)
+	}
+	hit, p0, p1 := testTorus.DoesRayIntersect(r)
+	if !hit || absFloat(p0.X+12) > 1e-3 || absFloat(p1.X-12) > 1e-3 {
+		t.Errorf("DoesRayIntersect = %v, %v, %v, want hits at x = -12 and 12", hit, p0, p1)
+	}
+}
+
+func TestTorusRayThroughHoleCenterMisses(t *testing.T) {
+	//down the axis of the ring, through the middle of the hole
+	r := rays.Ray{Origin: rays.Point{Y: -50}, Direction: rays.Point{Y: 1}}
+	if hit, p, _ := testTorus.DoesRayIntersect(r); hit {
+		t.Errorf("ray through the center of the hole hit the torus at %v", p)
+	}
+	if got := testTorus.hitDistances(r); len(got) != 0 {
+		t.Errorf("hitDistances = %v, want none", got)
+	}
+}
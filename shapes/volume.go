@@ -0,0 +1,118 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Volume fills a closed shape, such as a Circle or Box, with a participating medium like smoke, cloud or murky water.  Rays passing through the
+//Boundary are dimmed by the medium, and when lit (see NewLightSourceCircle) brightened by the light it scatters towards the viewer, with shapes
+//casting shadows into it.  Shadows cast by a volume are dimmed by the light it lets through instead of being solid.
+//What lies behind or inside the volume is found through ReflectiveObjects, so like reflective shapes volumes should be placed directly rather than instanced.
+type Volume struct {
+	Boundary Intersectable
+	Medium   Medium
+}
+
+//Equals returns true if the 2 Intersectables are equivalent
+func (v Volume) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case Volume:
+		compare := i.(Volume)
+		return v.Boundary.Equals(compare.Boundary)
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect returns where the ray enters and leaves the boundary
+func (v Volume) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	return v.Boundary.DoesRayIntersect(r)
+}
+
+//ColorAtPoint returns what is seen through the volume from cameraPosition, looking at p on its boundary
+func (v Volume) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return v.radiance(p, rays.RayDifferential{Ray: rays.Ray{Origin: cameraPosition}}, nil)
+}
+
+//ColorAtPointDifferential returns what is seen through the volume along r, looking at p on its boundary.  Unlit volumes only dim what is behind them.
+func (v Volume) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return v.radiance(p, r, nil)
+}
+
+//NormalAtPoint returns the boundary's normal at p
+func (v Volume) NormalAtPoint(p rays.Point) rays.Ray {
+	return v.Boundary.NormalAtPoint(p)
+}
+
+//GeometricNormalAtPoint returns the boundary's true surface normal at p
+func (v Volume) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	return GeometricNormal(v.Boundary, p)
+}
+
+//Bounds returns the box enclosing the boundary
+func (v Volume) Bounds() BoundingBox {
+	return BoundsOf(v.Boundary)
+}
+
+//Transmittance returns the fraction of light that passes through the medium along r, over the spans between its origin and distance that lie
+//inside the boundary
+func (v Volume) Transmittance(r rays.Ray, distance float32) rays.Point {
+	transmittance := rays.Point{X: 1, Y: 1, Z: 1}
+	for _, in := range IntervalsOf(v.Boundary, r) {
+		t0, t1 := maxFloat(in.Enter, 0), minFloat(in.Exit, distance)
+		if t0 < t1 {
+			transmittance = multiplyColors(transmittance, v.Medium.Transmittance(r, t0, t1))
+		}
+	}
+	return transmittance
+}
+
+//radiance returns the light arriving along r from p, where r enters (or, starting inside, leaves) the boundary: the light behind or inside
+//the volume dimmed by the medium, plus the light from lights the medium scatters along the way
+func (v Volume) radiance(p rays.Point, r rays.RayDifferential, lights []rays.Point) rays.Point {
+	ray := rays.Ray{Origin: r.Origin, Direction: rays.Normalize(r.Origin, p)}
+	hitT := rays.Magnitude(rays.Subtract(p, r.Origin))
+
+	//the span of the ray inside the boundary that p starts or ends, the one nearest p as p is only on the boundary to within rounding.
+	//A ray grazing the boundary has no span.
+	start, end, nearest := hitT, hitT, float32(math.Inf(1))
+	for _, in := range IntervalsOf(v.Boundary, ray) {
+		if gap := maxFloat(in.Enter-hitT, hitT-in.Exit); gap < nearest {
+			start, end, nearest = maxFloat(in.Enter, 0), in.Exit, gap
+		}
+	}
+
+	//the nearest other shape along the ray, which may lie inside the volume
+	behind, behindT := rays.Point{}, float32(math.Inf(1))
+	var behindObject Intersectable
+	var behindPoint rays.Point
+	for _, e := range ReflectiveObjects {
+		if e.Equals(v) {
+			continue
+		}
+		if do, intersectPoint, _ := e.DoesRayIntersect(ray); do {
+			if t := rays.Magnitude(rays.Subtract(intersectPoint, ray.Origin)); t > start && t < behindT {
+				behindT, behindObject, behindPoint = t, e, intersectPoint
+			}
+		}
+	}
+	if behindObject != nil {
+		r.Ray = ray
+		behind = ColorAt(behindObject, behindPoint, r)
+		if behindT > end {
+			//the stretch between leaving the volume and reaching the shape is open air
+			beyond := rays.Ray{Origin: rays.Add(ray.Origin, rays.Multiply(ray.Direction, end)), Direction: ray.Direction}
+			behind = Fog.Apply(beyond, behindT-end, behind)
+		}
+	}
+
+	scattered, transmittance := singleScatter(v.Medium, ray, start, minFloat(end, behindT), lights)
+	return rays.Add(scattered, multiplyColors(behind, transmittance))
+}
+
+//volumeLight lights a Volume by the light it scatters from the light source
+func volumeLight(p rays.Point, r rays.RayDifferential, l Lighting) rays.Point {
+	return l.Inner.(Volume).radiance(p, r, []rays.Point{l.LightSource})
+}
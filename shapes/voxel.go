@@ -0,0 +1,353 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//VoxelGrid represents voxel art: a grid of equal cubes, each empty or filled with a color from Palette.  Voxels hold a palette index, where
+//0 is empty and palette entry i colors the voxels holding i.  Voxel x, y, z spans from Origin + (x, y, z)*VoxelSize to one VoxelSize further
+//along each axis.  Indexes past the end of the palette use Color.  Voxel data is shared, so copies of a VoxelGrid see the same voxels.
+//Create one with NewVoxelGrid, or NewSparseVoxelGrid for large grids that are mostly empty.
+type VoxelGrid struct {
+	Origin    rays.Point
+	VoxelSize float32
+	Palette   []rays.Point
+	Color     rays.Point
+	Material
+	size  [3]int
+	cells *voxelCells
+}
+
+//voxelCells holds the palette index of every voxel, in a flat slice ordered x fastest then y then z when dense, or in a map of the filled voxels
+//when sparse
+type voxelCells struct {
+	dense  []uint8
+	sparse map[[3]int]uint8
+}
+
+//NewVoxelGrid creates an empty grid of sizeX*sizeY*sizeZ voxels that stores one byte per voxel.  Fill it with Set.
+func NewVoxelGrid(sizeX int, sizeY int, sizeZ int, origin rays.Point, voxelSize float32, color rays.Point) (VoxelGrid, error) {
+	vg, err := newVoxelGrid(sizeX, sizeY, sizeZ, origin, voxelSize, color)
+	if err != nil {
+		return VoxelGrid{}, err
+	}
+	vg.cells.dense = make([]uint8, sizeX*sizeY*sizeZ)
+	return vg, nil
+}
+
+//NewSparseVoxelGrid creates an empty grid of sizeX*sizeY*sizeZ voxels that only stores the voxels that are filled, for large grids that are
+//mostly empty.  Looking voxels up is slower than in a dense grid.  Fill it with Set.
+func NewSparseVoxelGrid(sizeX int, sizeY int, sizeZ int, origin rays.Point, voxelSize float32, color rays.Point) (VoxelGrid, error) {
+	vg, err := newVoxelGrid(sizeX, sizeY, sizeZ, origin, voxelSize, color)
+	if err != nil {
+		return VoxelGrid{}, err
+	}
+	vg.cells.sparse = map[[3]int]uint8{}
+	return vg, nil
+}
+
+//newVoxelGrid checks the grid's dimensions, which must hold at most MaxInt32 voxels, and returns it without any voxel storage
+func newVoxelGrid(sizeX int, sizeY int, sizeZ int, origin rays.Point, voxelSize float32, color rays.Point) (VoxelGrid, error) {
+	if sizeX < 1 || sizeY < 1 || sizeZ < 1 {
+		return VoxelGrid{}, fmt.Errorf("voxel grid size %dx%dx%d must be at least 1 along each axis", sizeX, sizeY, sizeZ)
+	}
+	if sizeY > math.MaxInt32/sizeX || sizeZ > math.MaxInt32/(sizeX*sizeY) {
+		return VoxelGrid{}, fmt.Errorf("voxel grid size %dx%dx%d has more than %d voxels", sizeX, sizeY, sizeZ, math.MaxInt32)
+	}
+	if voxelSize <= 0 {
+		return VoxelGrid{}, errors.New("voxel size must be positive")
+	}
+	return VoxelGrid{Origin: origin, VoxelSize: voxelSize, Color: color, size: [3]int{sizeX, sizeY, sizeZ}, cells: &voxelCells{}}, nil
+}
+
+//Size returns the number of voxels along each axis
+func (vg VoxelGrid) Size() (sizeX int, sizeY int, sizeZ int) {
+	return vg.size[0], vg.size[1], vg.size[2]
+}
+
+//Set fills voxel x, y, z with palette index, or empties it if index is 0.  Returns an error if the voxel is outside the grid.
+func (vg VoxelGrid) Set(x int, y int, z int, index uint8) error {
+	cell := [3]int{x, y, z}
+	if !vg.inside(cell) {
+		return fmt.Errorf("voxel %d %d %d is outside the %dx%dx%d grid", x, y, z, vg.size[0], vg.size[1], vg.size[2])
+	}
+	switch {
+	case vg.cells.dense != nil:
+		vg.cells.dense[(z*vg.size[1]+y)*vg.size[0]+x] = index
+	case index == 0:
+		delete(vg.cells.sparse, cell)
+	default:
+		vg.cells.sparse[cell] = index
+	}
+	return nil
+}
+
+//Voxel returns the palette index of voxel x, y, z, or 0 if it is empty or outside the grid
+func (vg VoxelGrid) Voxel(x int, y int, z int) uint8 {
+	return vg.voxel([3]int{x, y, z})
+}
+
+//Equals returns true if the 2 Intersectables are equivalent.  Voxel grids are equal when they share the same voxel data.
+func (vg VoxelGrid) Equals(i Intersectable) bool {
+	switch i.(type) {
+	case VoxelGrid:
+		compare := i.(VoxelGrid)
+		return vg.cells == compare.cells && vg.Origin.Equals(compare.Origin) && vg.VoxelSize == compare.VoxelSize
+	default:
+		return false
+	}
+}
+
+//DoesRayIntersect walks the voxels along the ray with a 3d DDA (see: http://www.cse.yorku.ca/~amana/research/grid.pdf) until it moves from an
+//empty voxel into a filled one.  intersectPoint1 is where the ray leaves the run of filled voxels it entered.  A ray starting inside filled
+//voxels hits where it first leaves them, with both points the same.  Points are kept a hair inside the face the ray crossed, so a ray hitting
+//exactly on the edge between two faces still gets the normal of the one it came through.
+func (vg VoxelGrid) DoesRayIntersect(r rays.Ray) (doesIntersect bool, intersectPoint0 rays.Point, intersectPoint1 rays.Point) {
+	tNear, tFar, hit := vg.Bounds().Intersect(r)
+	if vg.cells == nil || !hit || tFar <= hitEpsilon {
+		return false, intersectPoint0, intersectPoint1
+	}
+	t := maxFloat(tNear, 0)
+
+	//the ray in grid coordinates, where each voxel is one unit across
+	o := vg.toGrid(r.Origin)
+	d := [3]float32{r.Direction.X / vg.VoxelSize, r.Direction.Y / vg.VoxelSize, r.Direction.Z / vg.VoxelSize}
+	var cell, step [3]int
+	var tMax, tDelta [3]float32
+	for axis := range cell {
+		cell[axis] = clampInt(int(math.Floor(float64(o[axis]+t*d[axis]))), 0, vg.size[axis]-1)
+		step[axis], tMax[axis], tDelta[axis] = ddaAxis(o[axis], d[axis], cell[axis])
+	}
+
+	//the entry point is kept with the voxel it enters and the axis of the face it crosses, -1 until the DDA takes its first step
+	var enterPoint rays.Point
+	hitPoint := func(t float32, cell [3]int, axis int) rays.Point {
+		return vg.snapToFace(rays.Add(r.Origin, rays.Multiply(r.Direction, t)), cell, axis)
+	}
+
+	//enter is where the ray entered the filled voxels it is in, or -1 if it is in an empty one.  inside is set instead when the ray starts
+	//within filled voxels, so the hit is where it leaves them.
+	enter, inside, crossed := float32(-1), false, -1
+	for vg.inside(cell) {
+		filled := vg.voxel(cell) != 0
+		switch {
+		case filled && enter < 0 && !inside:
+			if t > hitEpsilon {
+				enter, enterPoint = t, hitPoint(t, cell, crossed)
+			} else {
+				inside = true
+			}
+		case !filled && enter >= 0:
+			return true, enterPoint, hitPoint(t, cell, crossed)
+		case !filled && inside:
+			if t > hitEpsilon {
+				p := hitPoint(t, cell, crossed)
+				return true, p, p
+			}
+			inside = false
+		}
+
+		axis := 0
+		if tMax[1] < tMax[axis] {
+			axis = 1
+		}
+		if tMax[2] < tMax[axis] {
+			axis = 2
+		}
+		if tMax[axis] > tFar {
+			break
+		}
+		t, cell[axis], tMax[axis], crossed = tMax[axis], cell[axis]+step[axis], tMax[axis]+tDelta[axis], axis
+	}
+
+	far := rays.Add(r.Origin, rays.Multiply(r.Direction, tFar))
+	switch {
+	case enter >= 0:
+		return true, enterPoint, far
+	case inside && tFar > hitEpsilon:
+		return true, far, far
+	}
+	return false, intersectPoint0, intersectPoint1
+}
+
+//ColorAtPoint returns the color at a given point.
+func (vg VoxelGrid) ColorAtPoint(p rays.Point, cameraPosition rays.Point) rays.Point {
+	return vg.surfaceColor(vg.voxelColor(p), p, vg.Origin, vg)
+}
+
+//ColorAtPointDifferential returns the color at a given point as seen along r, filtering textures over the ray's footprint
+func (vg VoxelGrid) ColorAtPointDifferential(p rays.Point, r rays.RayDifferential) rays.Point {
+	return vg.filteredColor(vg.voxelColor(p), p, vg.Origin, vg, vg.GeometricNormalAtPoint(p).Direction, r)
+}
+
+//NormalAtPoint returns the surface normal for this intersectable shape at point p, perturbed by any normal or bump map
+func (vg VoxelGrid) NormalAtPoint(p rays.Point) rays.Ray {
+	return vg.shadingNormal(vg.GeometricNormalAtPoint(p), vg.Origin, vg)
+}
+
+//GeometricNormalAtPoint returns the inward, axis aligned normal of the voxel face p lies on
+func (vg VoxelGrid) GeometricNormalAtPoint(p rays.Point) rays.Ray {
+	_, axis, side, ok := vg.faceAtPoint(p)
+	var n [3]float32
+	if ok {
+		n[axis] = side
+	}
+	return rays.Ray{Origin: p, Direction: rays.Point{X: n[0], Y: n[1], Z: n[2]}}
+}
+
+//UVAtPoint maps each voxel face to the full [0,1] square, using the two axes the face spans, so a texture repeats once per voxel
+func (vg VoxelGrid) UVAtPoint(p rays.Point) (u float32, v float32) {
+	cell, axis, _, ok := vg.faceAtPoint(p)
+	if !ok {
+		return 0, 0
+	}
+	g := vg.toGrid(p)
+	for i := range g {
+		g[i] = clampFloat(g[i]-float32(cell[i]), 0, 1)
+	}
+	switch axis {
+	case 0:
+		return g[2], g[1]
+	case 1:
+		return g[0], g[2]
+	default:
+		return g[0], g[1]
+	}
+}
+
+//VertexColorAtPoint returns the palette color of the voxel p lies on, so textures can use it.  ok is false if its index has no palette entry.
+func (vg VoxelGrid) VertexColorAtPoint(p rays.Point) (color rays.Point, ok bool) {
+	cell, _, _, found := vg.faceAtPoint(p)
+	if !found {
+		return color, false
+	}
+	index := int(vg.voxel(cell))
+	if index >= len(vg.Palette) {
+		return color, false
+	}
+	return vg.Palette[index], true
+}
+
+//Bounds returns the box enclosing the whole grid
+func (vg VoxelGrid) Bounds() BoundingBox {
+	extent := rays.Point{X: float32(vg.size[0]), Y: float32(vg.size[1]), Z: float32(vg.size[2])}
+	return BoundingBox{Min: vg.Origin, Max: rays.Add(vg.Origin, rays.Multiply(extent, vg.VoxelSize))}
+}
+
+//tangentsAtPoint returns the directions the surface moves in as u and v increase
+func (vg VoxelGrid) tangentsAtPoint(p rays.Point) (dpdu rays.Point, dpdv rays.Point) {
+	_, axis, _, _ := vg.faceAtPoint(p)
+	switch axis {
+	case 0:
+		return rays.Point{Z: vg.VoxelSize}, rays.Point{Y: vg.VoxelSize}
+	case 1:
+		return rays.Point{X: vg.VoxelSize}, rays.Point{Z: vg.VoxelSize}
+	default:
+		return rays.Point{X: vg.VoxelSize}, rays.Point{Y: vg.VoxelSize}
+	}
+}
+
+//voxelColor returns the palette color of the voxel p lies on, or Color if there is none
+func (vg VoxelGrid) voxelColor(p rays.Point) rays.Point {
+	if color, ok := vg.VertexColorAtPoint(p); ok {
+		return color
+	}
+	return vg.Color
+}
+
+//faceAtPoint finds the voxel face p lies on, returning the filled voxel behind it, the face's axis (0=X, 1=Y, 2=Z), and side, the direction
+//along that axis from the face into the voxel.  Each axis is tried in order of how close p is to a boundary between voxels along it, taking
+//the first where one side is filled and the other empty.  Where p also lies on a boundary along another axis, as it does on the edge of a
+//voxel, the voxels on both sides of that boundary are tried.  ok is false if p is not on a face.
+func (vg VoxelGrid) faceAtPoint(p rays.Point) (cell [3]int, axis int, side float32, ok bool) {
+	const tolerance = 1e-4
+	g := vg.toGrid(p)
+	var low, high [3]int
+	var dist [3]float32
+	for i := range g {
+		low[i], high[i] = int(math.Floor(float64(g[i]-tolerance))), int(math.Floor(float64(g[i]+tolerance)))
+		dist[i] = absFloat(g[i] - float32(math.Round(float64(g[i]))))
+	}
+
+	tried := [3]bool{}
+	for n := 0; n < 3; n++ {
+		axis = -1
+		for i := range dist {
+			if !tried[i] && (axis < 0 || dist[i] < dist[axis]) {
+				axis = i
+			}
+		}
+		tried[axis] = true
+
+		boundary := int(math.Round(float64(g[axis])))
+		for x := low[0]; x <= high[0]; x++ {
+			for y := low[1]; y <= high[1]; y++ {
+				for z := low[2]; z <= high[2]; z++ {
+					above, below := [3]int{x, y, z}, [3]int{x, y, z}
+					above[axis], below[axis] = boundary, boundary-1
+					aboveFilled, belowFilled := vg.voxel(above) != 0, vg.voxel(below) != 0
+					switch {
+					case aboveFilled && !belowFilled:
+						return above, axis, 1, true
+					case belowFilled && !aboveFilled:
+						return below, axis, -1, true
+					}
+				}
+			}
+		}
+	}
+	return cell, 0, 0, false
+}
+
+//snapToFace moves p, which lies on the face of cell across axis, a hair away from the face's edges so faceAtPoint picks that face even where
+//the ray hit the edge shared with another face.  An axis of -1 picks the face of cell p is closest to.
+func (vg VoxelGrid) snapToFace(p rays.Point, cell [3]int, axis int) rays.Point {
+	const margin = 2e-3
+	g := vg.toGrid(p)
+	if axis < 0 {
+		best := float32(math.MaxFloat32)
+		for i := range g {
+			f := g[i] - float32(cell[i])
+			if d := minFloat(absFloat(f), absFloat(1-f)); d < best {
+				best, axis = d, i
+			}
+		}
+	}
+	for i := range g {
+		if i != axis {
+			g[i] = clampFloat(g[i], float32(cell[i])+margin, float32(cell[i]+1)-margin)
+		}
+	}
+	return rays.Add(vg.Origin, rays.Multiply(rays.Point{X: g[0], Y: g[1], Z: g[2]}, vg.VoxelSize))
+}
+
+//toGrid returns p in grid coordinates, where each voxel is one unit across and voxel 0, 0, 0 starts at the origin
+func (vg VoxelGrid) toGrid(p rays.Point) [3]float32 {
+	rel := rays.Multiply(rays.Subtract(p, vg.Origin), 1/vg.VoxelSize)
+	return [3]float32{rel.X, rel.Y, rel.Z}
+}
+
+//inside returns true if cell is within the grid
+func (vg VoxelGrid) inside(cell [3]int) bool {
+	for i, c := range cell {
+		if c < 0 || c >= vg.size[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//voxel returns the palette index of cell, or 0 if it is empty or outside the grid
+func (vg VoxelGrid) voxel(cell [3]int) uint8 {
+	if vg.cells == nil || !vg.inside(cell) {
+		return 0
+	}
+	if vg.cells.dense != nil {
+		return vg.cells.dense[(cell[2]*vg.size[1]+cell[1])*vg.size[0]+cell[0]]
+	}
+	return vg.cells.sparse[cell]
+}
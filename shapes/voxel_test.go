@@ -0,0 +1,118 @@
+package shapes
+
+import (
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+func TestNewVoxelGridTooLarge(t *testing.T) {
+	sizes := [][3]int{{2147483647, 2147483647, 4}, {1 << 20, 1 << 20, 1}, {1 << 11, 1 << 10, 1 << 10}}
+	for _, s := range sizes {
+		if _, err := NewVoxelGrid(s[0], s[1], s[2], rays.Point{}, 1, rays.Point{}); err == nil {
+			t.Errorf("NewVoxelGrid(%d, %d, %d) did not fail", s[0], s[1], s[2])
+		}
+		if _, err := NewSparseVoxelGrid(s[0], s[1], s[2], rays.Point{}, 1, rays.Point{}); err == nil {
+			t.Errorf("NewSparseVoxelGrid(%d, %d, %d) did not fail", s[0], s[1], s[2])
+		}
+	}
+}
+
+//testVoxelGrids returns a dense and a sparse 4x4x4 grid of unit voxels from the origin, with the given voxels filled
+func testVoxelGrids(t *testing.T, filled ...[3]int) map[string]VoxelGrid {
+	grids := map[string]VoxelGrid{}
+	for name, newGrid := range map[string]func(int, int, int, rays.Point, float32, rays.Point) (VoxelGrid, error){"dense": NewVoxelGrid, "sparse": NewSparseVoxelGrid} {
+		vg, err := newGrid(4, 4, 4, rays.Point{}, 1, rays.Point{X: 1, Y: 1, Z: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range filled {
+			if err := vg.Set(c[0], c[1], c[2], 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+		grids[name] = vg
+	}
+	return grids
+}
+
+//closeTo reports whether a and b are within tolerance of each other along every axis
+func closeTo(a rays.Point, b rays.Point, tolerance float32) bool {
+	return absFloat(a.X-b.X) <= tolerance && absFloat(a.Y-b.Y) <= tolerance && absFloat(a.Z-b.Z) <= tolerance
+}
+
+func TestVoxelGridAxisAlignedRay(t *testing.T) {
+	//passes the empty voxels in front, then through two filled ones in a row
+	r := rays.Ray{Origin: rays.Point{X: 2.5, Y: 1.5, Z: -10}, Direction: rays.Point{Z: 1}}
+	for name, vg := range testVoxelGrids(t, [3]int{2, 1, 1}, [3]int{2, 1, 2}) {
+		hit, p0, p1 := vg.DoesRayIntersect(r)
+		if !hit {
+			t.Errorf("%s: ray missed the filled voxels", name)
+			continue
+		}
+		if want := (rays.Point{X: 2.5, Y: 1.5, Z: 1}); !closeTo(p0, want, 1e-3) {
+			t.Errorf("%s: entered at %v, want %v", name, p0, want)
+		}
+		if want := (rays.Point{X: 2.5, Y: 1.5, Z: 3}); !closeTo(p1, want, 1e-3) {
+			t.Errorf("%s: left at %v, want %v", name, p1, want)
+		}
+		if n := vg.GeometricNormalAtPoint(p0).Direction; n != (rays.Point{Z: 1}) {
+			t.Errorf("%s: normal at entry = %v, want (0, 0, 1)", name, n)
+		}
+
+		//just beside the filled voxels
+		miss := rays.Ray{Origin: rays.Point{X: 1.5, Y: 1.5, Z: -10}, Direction: rays.Point{Z: 1}}
+		if hit, _, _ := vg.DoesRayIntersect(miss); hit {
+			t.Errorf("%s: ray through empty voxels hit", name)
+		}
+	}
+}
+
+func TestVoxelGridDiagonalRay(t *testing.T) {
+	//crosses several empty voxels along every axis before reaching the filled one
+	origin := rays.Point{X: 0.2, Y: 0.5, Z: -3}
+	r := rays.Ray{Origin: origin, Direction: rays.Normalize(origin, rays.Point{X: 2.7, Y: 2.4, Z: 2.2})}
+	tNear, tFar, _ := BoundingBox{Min: rays.Point{X: 2, Y: 2, Z: 2}, Max: rays.Point{X: 3, Y: 3, Z: 3}}.Intersect(r)
+	wantEnter := rays.Add(r.Origin, rays.Multiply(r.Direction, tNear))
+	wantLeave := rays.Add(r.Origin, rays.Multiply(r.Direction, tFar))
+	for name, vg := range testVoxelGrids(t, [3]int{2, 2, 2}) {
+		hit, p0, p1 := vg.DoesRayIntersect(r)
+		if !hit {
+			t.Errorf("%s: ray missed the filled voxel", name)
+			continue
+		}
+		//hit points are nudged in from the face's edges by a fraction of a voxel
+		if !closeTo(p0, wantEnter, 5e-3) || !closeTo(p1, wantLeave, 5e-3) {
+			t.Errorf("%s: hit %v to %v, want %v to %v", name, p0, p1, wantEnter, wantLeave)
+		}
+		if n := vg.GeometricNormalAtPoint(p0).Direction; n != (rays.Point{Z: 1}) {
+			t.Errorf("%s: normal at entry = %v, want (0, 0, 1)", name, n)
+		}
+	}
+}
+
+func TestVoxelGridEdgeHitNormal(t *testing.T) {
+	//both rays hit the edge of voxel 1, 1, 1 where its -Y and -Z faces meet, running along the face they do not cross
+	tests := []struct {
+		r    rays.Ray
+		want rays.Point
+	}{
+		{rays.Ray{Origin: rays.Point{X: 1.5, Y: 1, Z: -5}, Direction: rays.Point{Z: 1}}, rays.Point{Z: 1}},
+		{rays.Ray{Origin: rays.Point{X: 1.5, Y: -5, Z: 1}, Direction: rays.Point{Y: 1}}, rays.Point{Y: 1}},
+	}
+	for name, vg := range testVoxelGrids(t, [3]int{1, 1, 1}) {
+		for _, test := range tests {
+			hit, p, _ := vg.DoesRayIntersect(test.r)
+			if !hit {
+				t.Errorf("%s: ray from %v missed the edge", name, test.r.Origin)
+				continue
+			}
+			if !closeTo(p, rays.Point{X: 1.5, Y: 1, Z: 1}, 5e-3) {
+				t.Errorf("%s: ray from %v hit at %v, want (1.5, 1, 1)", name, test.r.Origin, p)
+			}
+			if n := vg.GeometricNormalAtPoint(p).Direction; n != test.want {
+				t.Errorf("%s: ray from %v got normal %v, want %v", name, test.r.Origin, n, test.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package textures
+
+import (
+	"errors"
+	"image"
+	//register the decoders used by LoadBitmap
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Filter selects how an Image texture is sampled between texel centers
+type Filter int
+
+const (
+	//Nearest returns the single closest texel
+	Nearest Filter = iota
+	//Bilinear blends the four closest texels
+	Bilinear
+	//Trilinear blends bilinear lookups from the two mip levels closest to the sample's footprint
+	Trilinear
+	//EWA applies an elliptically weighted average over the footprint, which keeps detail along the footprint's short axis at grazing angles
+	EWA
+)
+
+//maxAnisotropy limits how elongated an EWA footprint may be before it is widened, bounding the number of texels read
+const maxAnisotropy = 8
+
+//Wrap selects what an Image texture returns for UVs outside [0,1]
+type Wrap int
+
+const (
+	//Repeat tiles the image
+	Repeat Wrap = iota
+	//Clamp stretches the edge texels
+	Clamp
+)
+
+//Bitmap holds decoded pixel data and its mip pyramid.  A Bitmap is never modified after creation so any number of Image textures may share one.
+type Bitmap struct {
+	Width  int
+	Height int
+	pixels []rays.Point
+	mips   []*Bitmap //successively halved copies, down to 1x1
+}
+
+var bitmapCache = map[string]*Bitmap{}
+var bitmapCacheLock sync.Mutex
+
+//LoadBitmap decodes a PNG or JPEG file.  Each file is only loaded once, later calls with the same path return the shared Bitmap.
+func LoadBitmap(path string) (*Bitmap, error) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bitmapCacheLock.Lock()
+	defer bitmapCacheLock.Unlock()
+	if b, ok := bitmapCache[key]; ok {
+		return b, nil
+	}
+
+	f, err := os.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewBitmap(img)
+	if err != nil {
+		return nil, err
+	}
+	bitmapCache[key] = b
+	return b, nil
+}
+
+//NewBitmap converts an already decoded image into a Bitmap, with color channels in the range [0,1].  An image with no pixels is an error, there would be nothing to sample.
+func NewBitmap(img image.Image) (*Bitmap, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, errors.New("image has no pixels")
+	}
+	b := &Bitmap{Width: bounds.Dx(), Height: bounds.Dy()}
+	b.pixels = make([]rays.Point, b.Width*b.Height)
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			r, g, bl, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			b.pixels[y*b.Width+x] = rays.Point{X: float32(r) / 0xffff, Y: float32(g) / 0xffff, Z: float32(bl) / 0xffff}
+		}
+	}
+	b.buildMips()
+	return b, nil
+}
+
+//buildMips box filters the bitmap down by half repeatedly until it is a single texel
+func (b *Bitmap) buildMips() {
+	prev := b
+	for prev.Width > 1 || prev.Height > 1 {
+		next := &Bitmap{Width: maxInt(prev.Width/2, 1), Height: maxInt(prev.Height/2, 1)}
+		next.pixels = make([]rays.Point, next.Width*next.Height)
+		for y := 0; y < next.Height; y++ {
+			y0, y1 := minInt(2*y, prev.Height-1), minInt(2*y+1, prev.Height-1)
+			for x := 0; x < next.Width; x++ {
+				x0, x1 := minInt(2*x, prev.Width-1), minInt(2*x+1, prev.Width-1)
+				sum := rays.Add(rays.Add(prev.Texel(x0, y0), prev.Texel(x1, y0)), rays.Add(prev.Texel(x0, y1), prev.Texel(x1, y1)))
+				next.pixels[y*next.Width+x] = rays.Divide(sum, 4)
+			}
+		}
+		b.mips = append(b.mips, next)
+		prev = next
+	}
+}
+
+//Levels returns the number of mip levels, including the full size bitmap
+func (b *Bitmap) Levels() int {
+	return len(b.mips) + 1
+}
+
+//Level returns mip level i, where level 0 is the full size bitmap
+func (b *Bitmap) Level(i int) *Bitmap {
+	if i <= 0 {
+		return b
+	}
+	if i > len(b.mips) {
+		i = len(b.mips)
+	}
+	return b.mips[i-1]
+}
+
+//Texel returns the pixel at column x and row y, which must be within the bitmap
+func (b *Bitmap) Texel(x int, y int) rays.Point {
+	return b.pixels[y*b.Width+x]
+}
+
+//Image is a texture that looks up the shape's UV coordinates in a bitmap.  U runs left to right and V runs top to bottom.
+type Image struct {
+	Bitmap *Bitmap
+	Filter Filter
+	Wrap   Wrap
+}
+
+//ColorAt returns the filtered bitmap color at the sample's UV coordinates.
+//Trilinear and EWA filtering use the sample's footprint, falling back to bilinear filtering of the full size bitmap when it is unknown.
+func (t Image) ColorAt(s Sample) rays.Point {
+	switch t.Filter {
+	case Trilinear:
+		width := maxFloat(length2(s.DUDX, s.DVDX), length2(s.DUDY, s.DVDY))
+		return t.trilinear(s.U, s.V, width)
+	case EWA:
+		return t.ewa(s)
+	default:
+		return sampleBitmap(t.Bitmap, s.U, s.V, t.Filter, t.Wrap)
+	}
+}
+
+//trilinear blends the two mip levels whose texel size best matches width (in UV units)
+func (t Image) trilinear(u float32, v float32, width float32) rays.Point {
+	levels := t.Bitmap.Levels()
+	texels := width * float32(maxInt(t.Bitmap.Width, t.Bitmap.Height))
+	if texels <= 1 {
+		return sampleBitmap(t.Bitmap, u, v, Bilinear, t.Wrap)
+	}
+
+	level := float32(math.Log2(float64(texels)))
+	if level >= float32(levels-1) {
+		return t.Bitmap.Level(levels-1).Texel(0, 0)
+	}
+	i := int(level)
+	fine := sampleBitmap(t.Bitmap.Level(i), u, v, Bilinear, t.Wrap)
+	coarse := sampleBitmap(t.Bitmap.Level(i+1), u, v, Bilinear, t.Wrap)
+	return Lerp(fine, coarse, level-float32(i))
+}
+
+//ewa picks mip levels from the short axis of the footprint ellipse and filters each along the ellipse,
+//following http://www.pbr-book.org/3ed-2018/Texture/Image_Texture.html#EllipticallyWeightedAverage
+func (t Image) ewa(s Sample) rays.Point {
+	du0, dv0, du1, dv1 := s.DUDX, s.DVDX, s.DUDY, s.DVDY
+	if length2(du0, dv0) < length2(du1, dv1) {
+		du0, dv0, du1, dv1 = du1, dv1, du0, dv0
+	}
+	major, minor := length2(du0, dv0), length2(du1, dv1)
+
+	//clamp the eccentricity so very thin ellipses do not read thousands of texels
+	if minor*maxAnisotropy < major && minor > 0 {
+		scale := major / (minor * maxAnisotropy)
+		du1, dv1 = du1*scale, dv1*scale
+		minor *= scale
+	}
+	if minor == 0 {
+		return sampleBitmap(t.Bitmap, s.U, s.V, Bilinear, t.Wrap)
+	}
+
+	levels := t.Bitmap.Levels()
+	lod := maxFloat(0, float32(math.Log2(float64(minor*float32(maxInt(t.Bitmap.Width, t.Bitmap.Height))))))
+	i := int(lod)
+	if i >= levels-1 {
+		return t.Bitmap.Level(levels-1).Texel(0, 0)
+	}
+	fine := ewaLevel(t.Bitmap.Level(i), t.Wrap, s.U, s.V, du0, dv0, du1, dv1)
+	coarse := ewaLevel(t.Bitmap.Level(i+1), t.Wrap, s.U, s.V, du0, dv0, du1, dv1)
+	return Lerp(fine, coarse, lod-float32(i))
+}
+
+//ewaLevel sums the texels of b inside the footprint ellipse with a gaussian falloff
+func ewaLevel(b *Bitmap, wrap Wrap, u float32, v float32, du0 float32, dv0 float32, du1 float32, dv1 float32) rays.Point {
+	const alpha = 2
+	w, h := float64(b.Width), float64(b.Height)
+	s, t := float64(u)*w-0.5, float64(v)*h-0.5
+	ds0, dt0 := float64(du0)*w, float64(dv0)*h
+	ds1, dt1 := float64(du1)*w, float64(dv1)*h
+
+	//implicit ellipse coefficients, the +1 keeps the ellipse at least one texel across
+	A := dt0*dt0 + dt1*dt1 + 1
+	B := -2 * (ds0*dt0 + ds1*dt1)
+	C := ds0*ds0 + ds1*ds1 + 1
+	invF := 1 / (A*C - B*B*0.25)
+	A, B, C = A*invF, B*invF, C*invF
+
+	det := -B*B + 4*A*C
+	invDet := 1 / det
+	uSqrt, vSqrt := math.Sqrt(det*C), math.Sqrt(A*det)
+	s0, s1 := int(math.Ceil(s-2*invDet*uSqrt)), int(math.Floor(s+2*invDet*uSqrt))
+	t0, t1 := int(math.Ceil(t-2*invDet*vSqrt)), int(math.Floor(t+2*invDet*vSqrt))
+
+	var sum rays.Point
+	var weights float32
+	for it := t0; it <= t1; it++ {
+		tt := float64(it) - t
+		for is := s0; is <= s1; is++ {
+			ss := float64(is) - s
+			r2 := A*ss*ss + B*ss*tt + C*tt*tt
+			if r2 < 1 {
+				weight := float32(math.Exp(-alpha*r2) - math.Exp(-alpha))
+				sum = rays.Add(sum, rays.Multiply(b.Texel(wrapIndex(is, b.Width, wrap), wrapIndex(it, b.Height, wrap)), weight))
+				weights += weight
+			}
+		}
+	}
+	if weights == 0 {
+		return sampleBitmap(b, u, v, Bilinear, wrap)
+	}
+	return rays.Divide(sum, weights)
+}
+
+func sampleBitmap(b *Bitmap, u float32, v float32, filter Filter, wrap Wrap) rays.Point {
+	x := u*float32(b.Width) - 0.5
+	y := v*float32(b.Height) - 0.5
+
+	if filter == Nearest {
+		return b.Texel(wrapIndex(int(math.Floor(float64(x+0.5))), b.Width, wrap), wrapIndex(int(math.Floor(float64(y+0.5))), b.Height, wrap))
+	}
+
+	fx, fy := math.Floor(float64(x)), math.Floor(float64(y))
+	tx, ty := x-float32(fx), y-float32(fy)
+	x0, y0 := wrapIndex(int(fx), b.Width, wrap), wrapIndex(int(fy), b.Height, wrap)
+	x1, y1 := wrapIndex(int(fx)+1, b.Width, wrap), wrapIndex(int(fy)+1, b.Height, wrap)
+
+	top := Lerp(b.Texel(x0, y0), b.Texel(x1, y0), tx)
+	bottom := Lerp(b.Texel(x0, y1), b.Texel(x1, y1), tx)
+	return Lerp(top, bottom, ty)
+}
+
+//wrapIndex maps texel index i into [0,size) using the wrap mode
+func wrapIndex(i int, size int, wrap Wrap) int {
+	if wrap == Clamp {
+		if i < 0 {
+			return 0
+		}
+		if i >= size {
+			return size - 1
+		}
+		return i
+	}
+	i %= size
+	if i < 0 {
+		i += size
+	}
+	return i
+}
+
+func length2(x float32, y float32) float32 {
+	return float32(math.Sqrt(float64(x*x + y*y)))
+}
+
+func maxFloat(a float32, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
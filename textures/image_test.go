@@ -0,0 +1,31 @@
+package textures
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+func TestNewBitmap(t *testing.T) {
+	img := image.NewRGBA(image.Rect(3, 5, 5, 6))
+	img.Set(4, 5, color.RGBA{R: 255, A: 255})
+	b, err := NewBitmap(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Width != 2 || b.Height != 1 || b.Levels() != 2 {
+		t.Fatalf("bitmap is %dx%d with %d levels, want 2x1 with 2", b.Width, b.Height, b.Levels())
+	}
+	//pixels are counted from the image's bounds, not from 0
+	if got := b.Texel(1, 0); got != (rays.Point{X: 1}) {
+		t.Errorf("texel 1,0 = %v, want red", got)
+	}
+
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 0, 0), image.Rect(0, 0, 4, 0), image.Rect(2, 2, 2, 7)} {
+		if _, err := NewBitmap(image.NewRGBA(r)); err == nil {
+			t.Errorf("NewBitmap accepted an image of %v", r)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package textures
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Noise describes a smooth pseudo-random scalar field over 3d space, returning values roughly in the range [-1,1]
+type Noise interface {
+	At(p rays.Point) float32
+}
+
+//Perlin is 3d gradient noise as described in Ken Perlin's "Improving Noise": https://mrl.cs.nyu.edu/~perlin/paper445.pdf
+type Perlin struct {
+	perm [512]uint8
+}
+
+//NewPerlin creates Perlin noise whose lattice gradients are shuffled by seed.  The same seed always produces the same noise on every machine.
+func NewPerlin(seed int64) *Perlin {
+	n := &Perlin{}
+	n.perm = permutation(seed)
+	return n
+}
+
+//At returns the noise value at point p
+func (n *Perlin) At(p rays.Point) float32 {
+	x, y, z := float64(p.X), float64(p.Y), float64(p.Z)
+	fx, fy, fz := math.Floor(x), math.Floor(y), math.Floor(z)
+	X, Y, Z := int(fx)&255, int(fy)&255, int(fz)&255
+	x, y, z = x-fx, y-fy, z-fz
+	u, v, w := fade(x), fade(y), fade(z)
+
+	perm := &n.perm
+	A := int(perm[X]) + Y
+	AA := int(perm[A]) + Z
+	AB := int(perm[A+1]) + Z
+	B := int(perm[X+1]) + Y
+	BA := int(perm[B]) + Z
+	BB := int(perm[B+1]) + Z
+
+	res := lerp(w,
+		lerp(v,
+			lerp(u, grad(perm[AA], x, y, z), grad(perm[BA], x-1, y, z)),
+			lerp(u, grad(perm[AB], x, y-1, z), grad(perm[BB], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad(perm[AA+1], x, y, z-1), grad(perm[BA+1], x-1, y, z-1)),
+			lerp(u, grad(perm[AB+1], x, y-1, z-1), grad(perm[BB+1], x-1, y-1, z-1))))
+	return float32(res)
+}
+
+//Simplex is 3d simplex noise, following Stefan Gustavson's "Simplex noise demystified": https://weber.itn.liu.se/~stegu/simplexnoise/simplexnoise.pdf
+//It has fewer directional artifacts than Perlin noise and is cheaper to evaluate.
+type Simplex struct {
+	perm [512]uint8
+}
+
+//NewSimplex creates simplex noise whose gradients are shuffled by seed.  The same seed always produces the same noise on every machine.
+func NewSimplex(seed int64) *Simplex {
+	n := &Simplex{}
+	n.perm = permutation(seed)
+	return n
+}
+
+//At returns the noise value at point p
+func (n *Simplex) At(p rays.Point) float32 {
+	const F3 = 1.0 / 3.0
+	const G3 = 1.0 / 6.0
+	x, y, z := float64(p.X), float64(p.Y), float64(p.Z)
+
+	//skew the input space to find which simplex cell we are in
+	s := (x + y + z) * F3
+	i, j, k := math.Floor(x+s), math.Floor(y+s), math.Floor(z+s)
+	t := (i + j + k) * G3
+	x0, y0, z0 := x-(i-t), y-(j-t), z-(k-t)
+
+	//find which of the six tetrahedra of the cube we are in
+	var i1, j1, k1, i2, j2, k2 float64
+	if x0 >= y0 {
+		if y0 >= z0 {
+			i1, j1, k1, i2, j2, k2 = 1, 0, 0, 1, 1, 0
+		} else if x0 >= z0 {
+			i1, j1, k1, i2, j2, k2 = 1, 0, 0, 1, 0, 1
+		} else {
+			i1, j1, k1, i2, j2, k2 = 0, 0, 1, 1, 0, 1
+		}
+	} else {
+		if y0 < z0 {
+			i1, j1, k1, i2, j2, k2 = 0, 0, 1, 0, 1, 1
+		} else if x0 < z0 {
+			i1, j1, k1, i2, j2, k2 = 0, 1, 0, 0, 1, 1
+		} else {
+			i1, j1, k1, i2, j2, k2 = 0, 1, 0, 1, 1, 0
+		}
+	}
+
+	x1, y1, z1 := x0-i1+G3, y0-j1+G3, z0-k1+G3
+	x2, y2, z2 := x0-i2+2*G3, y0-j2+2*G3, z0-k2+2*G3
+	x3, y3, z3 := x0-1+3*G3, y0-1+3*G3, z0-1+3*G3
+
+	perm := &n.perm
+	ii, jj, kk := int(i)&255, int(j)&255, int(k)&255
+	gi0 := perm[ii+int(perm[jj+int(perm[kk])])]
+	gi1 := perm[ii+int(i1)+int(perm[jj+int(j1)+int(perm[kk+int(k1)])])]
+	gi2 := perm[ii+int(i2)+int(perm[jj+int(j2)+int(perm[kk+int(k2)])])]
+	gi3 := perm[ii+1+int(perm[jj+1+int(perm[kk+1])])]
+
+	res := corner(gi0, x0, y0, z0) + corner(gi1, x1, y1, z1) + corner(gi2, x2, y2, z2) + corner(gi3, x3, y3, z3)
+	return float32(32 * res)
+}
+
+//corner returns the contribution of one simplex corner
+func corner(hash uint8, x float64, y float64, z float64) float64 {
+	t := 0.6 - x*x - y*y - z*z
+	if t < 0 {
+		return 0
+	}
+	t *= t
+	return t * t * grad(hash, x, y, z)
+}
+
+//FBM returns fractal Brownian motion: the sum of octaves of n, each at lacunarity times the frequency and gain times the amplitude of the last.
+//The result is normalized back into roughly [-1,1].
+func FBM(n Noise, p rays.Point, octaves int, lacunarity float32, gain float32) float32 {
+	var sum, norm float32
+	amplitude := float32(1)
+	for i := 0; i < octaves; i++ {
+		sum += amplitude * n.At(p)
+		norm += amplitude
+		amplitude *= gain
+		p = rays.Multiply(p, lacunarity)
+	}
+	if norm == 0 {
+		return 0
+	}
+	return sum / norm
+}
+
+//Turbulence is FBM built from the absolute value of each octave, giving the sharp creases used by marble and fire, in the range [0,1]
+func Turbulence(n Noise, p rays.Point, octaves int, lacunarity float32, gain float32) float32 {
+	var sum, norm float32
+	amplitude := float32(1)
+	for i := 0; i < octaves; i++ {
+		sum += amplitude * float32(math.Abs(float64(n.At(p))))
+		norm += amplitude
+		amplitude *= gain
+		p = rays.Multiply(p, lacunarity)
+	}
+	if norm == 0 {
+		return 0
+	}
+	return sum / norm
+}
+
+//RampStop is a single color in a ColorRamp
+type RampStop struct {
+	Position float32
+	Color    rays.Point
+}
+
+//ColorRamp maps a value to a color by interpolating between stops, which must be sorted by Position
+type ColorRamp []RampStop
+
+//At returns the color at value f, values outside the stops take the color of the nearest end
+func (r ColorRamp) At(f float32) rays.Point {
+	if len(r) == 0 {
+		return rays.Point{X: f, Y: f, Z: f}
+	}
+	if f <= r[0].Position {
+		return r[0].Color
+	}
+	for i := 1; i < len(r); i++ {
+		if f <= r[i].Position {
+			a, b := r[i-1], r[i]
+			return Lerp(a.Color, b.Color, (f-a.Position)/(b.Position-a.Position))
+		}
+	}
+	return r[len(r)-1].Color
+}
+
+//Fractal colors a surface by FBM (or turbulence) noise mapped through a color ramp, useful for clouds, dirt and as a bump height.
+//Scale is the noise frequency, an empty Ramp gives grayscale.
+type Fractal struct {
+	Noise     Noise
+	Scale     float32
+	Octaves   int
+	Turbulent bool
+	Ramp      ColorRamp
+}
+
+//ColorAt returns the ramp color of the noise at the sample
+func (t Fractal) ColorAt(s Sample) rays.Point {
+	p := rays.Multiply(s.P, t.Scale)
+	if t.Turbulent {
+		return t.Ramp.At(Turbulence(t.Noise, p, t.Octaves, 2, 0.5))
+	}
+	return t.Ramp.At(0.5 + 0.5*FBM(t.Noise, p, t.Octaves, 2, 0.5))
+}
+
+//Marble produces veins by bending sine bands along the X axis with turbulence.
+//Scale is the frequency of the bands, Distortion how far turbulence pushes them about.
+type Marble struct {
+	Noise      Noise
+	Scale      float32
+	Octaves    int
+	Distortion float32
+	Ramp       ColorRamp
+}
+
+//ColorAt returns the marble color at the sample
+func (t Marble) ColorAt(s Sample) rays.Point {
+	p := rays.Multiply(s.P, t.Scale)
+	turb := Turbulence(t.Noise, p, t.Octaves, 2, 0.5)
+	f := 0.5 + 0.5*math.Sin(float64(p.X+t.Distortion*turb)*math.Pi)
+	return t.Ramp.At(float32(f))
+}
+
+//Wood produces growth rings around the Y axis, wobbled by noise.
+//Scale is the number of rings per world unit, Distortion how much noise warps them.
+type Wood struct {
+	Noise      Noise
+	Scale      float32
+	Octaves    int
+	Distortion float32
+	Ramp       ColorRamp
+}
+
+//ColorAt returns the wood color at the sample
+func (t Wood) ColorAt(s Sample) rays.Point {
+	p := rays.Multiply(s.P, t.Scale)
+	radius := float32(math.Sqrt(float64(p.X*p.X + p.Z*p.Z)))
+	radius += t.Distortion * FBM(t.Noise, p, t.Octaves, 2, 0.5)
+	return t.Ramp.At(fract(radius))
+}
+
+//permutation returns Perlin's doubled permutation table shuffled by a seeded generator.
+//A local splitmix64 is used instead of math/rand so the table never changes between Go versions or platforms.
+func permutation(seed int64) [512]uint8 {
+	var p [512]uint8
+	for i := 0; i < 256; i++ {
+		p[i] = uint8(i)
+	}
+
+	state := uint64(seed)
+	for i := 255; i > 0; i-- {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		z ^= z >> 31
+		j := int(z % uint64(i+1))
+		p[i], p[j] = p[j], p[i]
+	}
+	for i := 0; i < 256; i++ {
+		p[256+i] = p[i]
+	}
+	return p
+}
+
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(t float64, a float64, b float64) float64 {
+	return a + t*(b-a)
+}
+
+//grad returns the dot product of (x,y,z) with one of the 12 cube edge gradients, picked by hash
+func grad(hash uint8, x float64, y float64, z float64) float64 {
+	h := hash & 15
+	u := x
+	if h >= 8 {
+		u = y
+	}
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
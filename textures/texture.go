@@ -0,0 +1,164 @@
+package textures
+
+import (
+	"math"
+
+	"github.com/flabbergasted/RayTracer/rays"
+)
+
+//Sample describes the surface location a texture is being evaluated at
+type Sample struct {
+	P rays.Point //hit point, in either world or object space depending on the shape's material
+	U float32    //surface parameterization, zero if the shape does not provide one
+	V float32
+
+	//change in U and V across one pixel in screen x and y, all zero when the footprint is unknown
+	DUDX, DVDX float32
+	DUDY, DVDY float32
+
+	VertexColor rays.Point //color interpolated from the shape's vertices, zero if the shape does not provide one
+}
+
+//Texture describes a color that varies over the surface of a shape
+type Texture interface {
+	ColorAt(s Sample) rays.Point
+}
+
+//Solid is a texture with a single color everywhere
+type Solid struct {
+	Color rays.Point
+}
+
+//ColorAt returns the solid color
+func (t Solid) ColorAt(s Sample) rays.Point {
+	return t.Color
+}
+
+//VertexColor is a texture that shows the colors stored at the shape's vertices, such as a mesh loaded with per-vertex colors
+type VertexColor struct{}
+
+//ColorAt returns the sample's vertex color
+func (t VertexColor) ColorAt(s Sample) rays.Point {
+	return s.VertexColor
+}
+
+//Modulate multiplies the colors of two textures channel by channel, such as an image tinted by a color or by the vertex colors
+type Modulate struct {
+	A Texture
+	B Texture
+}
+
+//ColorAt returns the product of A and B
+func (t Modulate) ColorAt(s Sample) rays.Point {
+	a, b := t.A.ColorAt(s), t.B.ColorAt(s)
+	return rays.Point{X: a.X * b.X, Y: a.Y * b.Y, Z: a.Z * b.Z}
+}
+
+//Stripes alternates between two textures in bands perpendicular to Axis.
+//Duty is the fraction (0-1) of each Period covered by A, the rest is covered by B.
+type Stripes struct {
+	Axis   rays.Point
+	Period float32
+	Duty   float32
+	A      Texture
+	B      Texture
+}
+
+//ColorAt returns A or B depending on which band the sample falls in
+func (t Stripes) ColorAt(s Sample) rays.Point {
+	if fract(rays.DotProduct(s.P, t.Axis)/t.Period) < t.Duty {
+		return t.A.ColorAt(s)
+	}
+	return t.B.ColorAt(s)
+}
+
+//Checkerboard alternates between two textures in cubes of edge length Size
+type Checkerboard struct {
+	Size float32
+	A    Texture
+	B    Texture
+}
+
+//ColorAt returns A or B depending on which cube the sample falls in
+func (t Checkerboard) ColorAt(s Sample) rays.Point {
+	x := math.Floor(float64(s.P.X / t.Size))
+	y := math.Floor(float64(s.P.Y / t.Size))
+	z := math.Floor(float64(s.P.Z / t.Size))
+	if int64(x+y+z)&1 == 0 {
+		return t.A.ColorAt(s)
+	}
+	return t.B.ColorAt(s)
+}
+
+//Gradient blends linearly from A to B along Axis, between the distances Start and End from the origin
+type Gradient struct {
+	Axis  rays.Point
+	Start float32
+	End   float32
+	A     Texture
+	B     Texture
+}
+
+//ColorAt returns the blend of A and B at the sample's position along the axis
+func (t Gradient) ColorAt(s Sample) rays.Point {
+	f := clamp((rays.DotProduct(s.P, t.Axis)-t.Start)/(t.End-t.Start), 0, 1)
+	return Lerp(t.A.ColorAt(s), t.B.ColorAt(s), f)
+}
+
+//Rings alternates between two textures in concentric cylinders around Axis (a line through the origin).
+//Duty is the fraction (0-1) of each Period covered by A, the rest is covered by B.
+type Rings struct {
+	Axis   rays.Point
+	Period float32
+	Duty   float32
+	A      Texture
+	B      Texture
+}
+
+//ColorAt returns A or B depending on which ring the sample falls in
+func (t Rings) ColorAt(s Sample) rays.Point {
+	along := rays.Multiply(t.Axis, rays.DotProduct(s.P, t.Axis))
+	dist := rays.Magnitude(rays.Subtract(s.P, along))
+	if fract(dist/t.Period) < t.Duty {
+		return t.A.ColorAt(s)
+	}
+	return t.B.ColorAt(s)
+}
+
+//UV evaluates the wrapped texture in the shape's 2d surface parameterization instead of 3d space,
+//by handing it the point (U, V, 0).  This lets any pattern above be used as a UV pattern.
+type UV struct {
+	Inner Texture
+}
+
+//ColorAt evaluates the inner texture at the sample's UV coordinates
+func (t UV) ColorAt(s Sample) rays.Point {
+	uv := s
+	uv.P = rays.Point{X: s.U, Y: s.V, Z: 0}
+	return t.Inner.ColorAt(uv)
+}
+
+//Lerp returns the linear interpolation between colors a and b, f=0 returns a and f=1 returns b
+func Lerp(a rays.Point, b rays.Point, f float32) rays.Point {
+	return rays.Add(rays.Multiply(a, 1-f), rays.Multiply(b, f))
+}
+
+//Luminance returns the perceived brightness of color c, used wherever a texture stands in for a scalar such as a height
+func Luminance(c rays.Point) float32 {
+	return 0.2126*c.X + 0.7152*c.Y + 0.0722*c.Z
+}
+
+//fract returns the fractional part of v, always in the range [0,1) so negative coordinates repeat the same pattern as positive ones
+func fract(v float32) float32 {
+	return v - float32(math.Floor(float64(v)))
+}
+
+func clamp(v float32, min float32, max float32) float32 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
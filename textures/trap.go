@@ -0,0 +1,20 @@
+package textures
+
+import "github.com/flabbergasted/RayTracer/rays"
+
+//Trapper is implemented by fractals that can report how close the orbit of a point came to their trap, in the range [0,1]
+type Trapper interface {
+	Trap(p rays.Point) float32
+}
+
+//OrbitTrap colors a fractal by mapping its orbit trap value through Ramp.  The trap is evaluated at the sample point, so the shape
+//must use world space textures unless the fractal is defined around the origin.
+type OrbitTrap struct {
+	Fractal Trapper
+	Ramp    ColorRamp
+}
+
+//ColorAt returns the ramp color for the orbit trap value at the sample point
+func (t OrbitTrap) ColorAt(s Sample) rays.Point {
+	return t.Ramp.At(t.Fractal.Trap(s.P))
+}
@@ -4,12 +4,15 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"runtime/pprof"
 	"strings"
 
 	"github.com/flabbergasted/RayTracer/rays"
+	"github.com/flabbergasted/RayTracer/scene"
 	"github.com/flabbergasted/RayTracer/shapes"
+	"github.com/flabbergasted/RayTracer/textures"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.2/glfw"
@@ -25,15 +28,13 @@ type pixel struct {
 	screenY  int
 }
 
-func generatePixelData(shapeSlice []shapes.Intersectable) []pixel {
+func generatePixelData(shapeSlice []shapes.Intersectable, cameraPos rays.Point) []pixel {
 	pixelCount := windowHeight * windowWidth
 	vertices := make([]pixel, pixelCount)
 	xIncrement := float32(2.0) / float32(windowWidth)
 	yIncrement := float32(2.0) / float32(windowHeight)
 	X := float32(-1.0)
 	Y := float32(1.0)
-	cameraPos := rays.Point{X: 400, Y: 300, Z: -1000}
-	var dir rays.Point
 
 	for i := 0; i < windowWidth; i++ {
 		X = float32(X) + xIncrement
@@ -42,18 +43,20 @@ func generatePixelData(shapeSlice []shapes.Intersectable) []pixel {
 			index := (i * windowHeight) + j
 			color := rays.Point{X: 0.0, Y: 0.0, Z: 0.0}
 
-			dir = rays.Normalize(cameraPos, rays.Point{X: float32(i), Y: float32(j), Z: 0})
-			cameraRay := rays.Ray{Origin: cameraPos, Direction: dir}
+			cameraRay := rays.NewCameraRayDifferential(cameraPos, rays.Point{X: float32(i), Y: float32(j), Z: 0})
 			distanceFromCamera := 100000
+			hitDistance := float32(math.Inf(1))
 			for _, e := range shapeSlice {
-				if do, intersectPoint, _ := e.DoesRayIntersect(cameraRay); do {
+				if do, intersectPoint, _ := e.DoesRayIntersect(cameraRay.Ray); do {
 					testDist := int(rays.Magnitude(rays.Subtract(intersectPoint, cameraPos)))
 					if testDist < distanceFromCamera {
-						color = e.ColorAtPoint(intersectPoint, cameraPos)
+						color = shapes.ColorAt(e, intersectPoint, cameraRay)
 						distanceFromCamera = testDist
+						hitDistance = rays.Magnitude(rays.Subtract(intersectPoint, cameraPos))
 					}
 				}
 			}
+			color = shapes.Fog.Apply(cameraRay.Ray, hitDistance, color)
 			vertices[index] = pixel{position: rays.Point{X: X, Y: Y, Z: 0.0}, rgb: color, screenX: i, screenY: j}
 		}
 		Y = float32(1.0)
@@ -81,34 +84,70 @@ func convertToFloat32Slice(p []pixel) []float32 {
 	}
 	return result
 }
-func generateShapes() []shapes.Intersectable {
+
+//generateShapes builds the scene graph, flattens it into world space and lights every shape.  Returns the shapes and the camera position.
+func generateShapes() ([]shapes.Intersectable, rays.Point) {
 	circSlice := make([]shapes.Intersectable, 0)
 
 	//light1 := shapes.Circle{Center: rays.Point{X: 400, Y: -600, Z: 0}, Radius: 5, Color: rays.Point{X: 1, Y: 1, Z: 1}}
 	light := shapes.Circle{Center: rays.Point{X: 250, Y: 250, Z: -250}, Radius: 5, Color: rays.Point{X: 1, Y: 1, Z: 1}}
-	triangle := shapes.NewLightSourceCircle(shapes.NewPlane(
+	floor, err := shapes.NewPlane(
 		rays.Point{X: 0, Y: 650, Z: 400},
 		rays.Point{X: 400, Y: 650, Z: 400},
 		rays.Point{X: 400, Y: 650, Z: 0},
-		rays.Point{X: 1, Y: 1, Z: 1}), light.Center)
-
-	cirReflect := shapes.NewLightSourceCircle(shapes.Circle{Center: rays.Point{X: 370, Y: 450, Z: 160}, Radius: 100, Color: rays.Point{X: 0, Y: 1, Z: 0}, Reflectivity: 1}, light.Center)
-	cirlitGreen2 := shapes.NewLightSourceCircle(shapes.Circle{Center: rays.Point{X: 525, Y: 500, Z: 50}, Radius: 100, Color: rays.Point{X: 0, Y: 1, Z: 0}}, light.Center)
-	cirlitStripe := shapes.NewLightSourceCircle(shapes.Circle{Center: rays.Point{X: 200, Y: 250, Z: 150}, Radius: 100, Color: rays.Point{X: 0.8, Y: 0.1, Z: 0.1}, YStripeColor: rays.Point{X: 0.3, Y: 0.0, Z: 0.3}, YStripeWidth: 3}, light.Center)
-	//cirlitWhite := shapes.NewLightSourceCircle(shapes.Circle{Center: rays.Point{X: 200, Y: 450, Z: 150}, Radius: 100, Color: rays.Point{X: 1, Y: 1, Z: 1}}, light.Center)
+		rays.Point{X: 1, Y: 1, Z: 1},
+		shapes.Infinite)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	cir := shapes.NewLightSourceCircle(shapes.Circle{Center: rays.Point{X: 0, Y: 450, Z: 0}, Radius: 100, Color: rays.Point{X: 0, Y: .3, Z: .4}}, light.Center)
-	cirAqua := shapes.NewLightSourceCircle(shapes.Circle{Center: rays.Point{X: 745, Y: 330, Z: 220}, Radius: 100, Color: rays.Point{X: 0, Y: 1, Z: 1}}, light.Center)
-	cir3 := shapes.NewLightSourceCircle(shapes.Circle{Center: rays.Point{X: 120, Y: 450, Z: 200}, Radius: 100, Color: rays.Point{X: 0.5, Y: 0.5, Z: 0}, XStripeColor: rays.Point{X: 0.0, Y: 0.0, Z: 1.0}, XStripeWidth: 3}, light.Center)
-	cir4 := shapes.NewLightSourceCircle(shapes.Circle{Center: rays.Point{X: 120, Y: 450, Z: 900}, Radius: 100, Color: rays.Point{X: 0.8, Y: 0.1, Z: 0.1}, YStripeColor: rays.Point{X: 0.3, Y: 0.0, Z: 0.3}, YStripeWidth: 3}, light.Center)
-	cir5 := shapes.NewLightSourceCircle(shapes.Circle{Center: rays.Point{X: 600, Y: 200, Z: 30}, Radius: 100, Color: rays.Point{X: 0.8, Y: 0.1, Z: 0.1}, XStripeColor: rays.Point{X: 0.0, Y: 0.0, Z: 1.0}, XStripeWidth: 3, YStripeColor: rays.Point{X: 0.3, Y: 0.0, Z: 0.3}, YStripeWidth: 3}, light.Center)
-	cir6 := shapes.NewLightSourceCircle(shapes.Circle{Center: rays.Point{X: 120, Y: 450, Z: 1500}, Radius: 100, Color: rays.Point{X: 1, Y: 1, Z: 1}}, light.Center)
-	circSlice = append(circSlice, cirlitGreen2, cir, cirAqua, cir3, cir4, cir5, cir6, cirReflect, cirlitStripe, triangle)
+	red := textures.Solid{Color: rays.Point{X: 0.8, Y: 0.1, Z: 0.1}}
+	blue := textures.Solid{Color: rays.Point{X: 0.0, Y: 0.0, Z: 1.0}}
+	purple := textures.Solid{Color: rays.Point{X: 0.3, Y: 0.0, Z: 0.3}}
+	purpleStripes := textures.Stripes{Axis: rays.Point{Y: 1}, Period: 10, Duty: 0.4, A: purple, B: red}
+	blueStripes := textures.Stripes{Axis: rays.Point{X: 1}, Period: 10, Duty: 0.4, A: blue, B: textures.Solid{Color: rays.Point{X: 0.5, Y: 0.5, Z: 0}}}
+	plaid := textures.Stripes{Axis: rays.Point{X: 1}, Period: 10, Duty: 0.4, A: blue, B: purpleStripes}
+
+	cirReflect := shapes.Circle{Center: rays.Point{X: 370, Y: 450, Z: 160}, Radius: 100, Color: rays.Point{X: 0, Y: 1, Z: 0}, Reflectivity: 1}
+	cirlitGreen2 := shapes.Circle{Center: rays.Point{X: 525, Y: 500, Z: 50}, Radius: 100, Color: rays.Point{X: 0, Y: 1, Z: 0}}
+	cirlitStripe := shapes.Circle{Center: rays.Point{X: 200, Y: 250, Z: 150}, Radius: 100, Material: shapes.Material{Texture: purpleStripes}}
+	//cirlitWhite := shapes.Circle{Center: rays.Point{X: 200, Y: 450, Z: 150}, Radius: 100, Color: rays.Point{X: 1, Y: 1, Z: 1}}
+
+	cir := shapes.Circle{Center: rays.Point{X: 0, Y: 450, Z: 0}, Radius: 100, Color: rays.Point{X: 0, Y: .3, Z: .4}}
+	cirAqua := shapes.Circle{Center: rays.Point{X: 745, Y: 330, Z: 220}, Radius: 100, Color: rays.Point{X: 0, Y: 1, Z: 1}}
+	cir3 := shapes.Circle{Center: rays.Point{X: 120, Y: 450, Z: 200}, Radius: 100, Material: shapes.Material{Texture: blueStripes}}
+	cir4 := shapes.Circle{Center: rays.Point{X: 120, Y: 450, Z: 900}, Radius: 100, Material: shapes.Material{Texture: purpleStripes}}
+	cir5 := shapes.Circle{Center: rays.Point{X: 600, Y: 200, Z: 30}, Radius: 100, Material: shapes.Material{Texture: plaid}}
+	cir6 := shapes.Circle{Center: rays.Point{X: 120, Y: 450, Z: 1500}, Radius: 100, Color: rays.Point{X: 1, Y: 1, Z: 1}}
+
+	root := &scene.Group{Name: "root", Children: []scene.Node{
+		&scene.Camera{Name: "camera", Position: rays.Point{X: 400, Y: 300, Z: -1000}, LookAt: rays.Point{X: 400, Y: 300, Z: 0}},
+		&scene.Light{Name: "light", Position: light.Center, Color: light.Color, Intensity: 1},
+		&scene.ShapeNode{Name: "green2", Shape: cirlitGreen2},
+		&scene.ShapeNode{Name: "teal", Shape: cir},
+		&scene.ShapeNode{Name: "aqua", Shape: cirAqua},
+		&scene.ShapeNode{Name: "blueStripes", Shape: cir3},
+		&scene.ShapeNode{Name: "purpleStripesFar", Shape: cir4},
+		&scene.ShapeNode{Name: "plaid", Shape: cir5},
+		&scene.ShapeNode{Name: "white", Shape: cir6},
+		&scene.ShapeNode{Name: "mirror", Shape: cirReflect},
+		&scene.ShapeNode{Name: "purpleStripes", Shape: cirlitStripe},
+		&scene.ShapeNode{Name: "floor", Shape: floor},
+	}}
+
+	world, err := scene.Flatten(root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lightPos := world.Lights[0].Position
+	for _, e := range world.Shapes {
+		circSlice = append(circSlice, shapes.NewLightSourceCircle(e, lightPos))
+	}
 
 	shapes.ShadowObjects = circSlice
 	circSlice = append(circSlice, light)
 	shapes.ReflectiveObjects = circSlice
-	return circSlice
+	return circSlice, world.Cameras[0].Position
 }
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")